@@ -0,0 +1,103 @@
+package cloudcontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/labstack/gommon/log"
+)
+
+// ErrMissingCredentials is returned when a session is needed but no username or password is available.
+var ErrMissingCredentials = errors.New("missing username and/or password")
+
+// CredentialsProvider supplies the account credentials used to create a new session
+// when the current session token is rejected.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (username string, password string, err error)
+}
+
+// CredentialsFunc adapts a function to the CredentialsProvider interface.
+type CredentialsFunc func(ctx context.Context) (string, string, error)
+
+// Credentials calls f(ctx).
+func (f CredentialsFunc) Credentials(ctx context.Context) (string, string, error) {
+	return f(ctx)
+}
+
+// StaticCredentials is a CredentialsProvider with a fixed username and password.
+type StaticCredentials struct {
+	Username string
+	Password string
+}
+
+// Credentials returns the fixed username and password.
+func (s StaticCredentials) Credentials(context.Context) (string, string, error) {
+	if s.Username == "" || s.Password == "" {
+		return "", "", ErrMissingCredentials
+	}
+	return s.Username, s.Password, nil
+}
+
+// WithCredentials lets the client log in again and retry a request once
+// when Panasonic rejects the current session token.
+func WithCredentials(provider CredentialsProvider) Option {
+	return func(c *Client) {
+		c.credentials = provider
+	}
+}
+
+// WithSessionHook registers a function that is called with every new session,
+// e.g. to persist the token for later use.
+func WithSessionHook(hook func(types.Session)) Option {
+	return func(c *Client) {
+		c.sessionHook = hook
+	}
+}
+
+// reauthenticate creates a new session with the configured credentials, unless another
+// goroutine already replaced the rejected token in the meantime.
+func (c *Client) reauthenticate(ctx context.Context, rejectedToken string) error {
+	if c.authMu != nil {
+		// nil for clients not created with NewClient, which are not safe for concurrent use
+		c.authMu.Lock()
+		defer c.authMu.Unlock()
+	}
+
+	if c.Token() != rejectedToken {
+		return nil
+	}
+
+	username, password, err := c.credentials.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := c.CreateSessionContext(ctx, username, password); err != nil {
+		return err
+	}
+	return nil
+}
+
+// canReauthenticate reports whether a failed request to url may be retried after logging in again.
+func (c *Client) canReauthenticate(url string, err error) bool {
+	if c.credentials == nil || !errors.Is(err, ErrUnauthorized) {
+		return false
+	}
+	return url != types.UrlPathLogin && url != types.UrlPathValidate
+}
+
+// doAuthenticatedRequest sends a request and, if the session was rejected, logs in and retries it once.
+func (c *Client) doAuthenticatedRequest(ctx context.Context, method string, url string, postbody []byte) ([]byte, error) {
+	token := c.Token()
+	body, err := c.doRequest(ctx, method, url, postbody)
+	if err == nil || !c.canReauthenticate(url, err) {
+		return body, err
+	}
+
+	log.Debugf("session rejected on %s, creating a new session", url)
+	if authErr := c.reauthenticate(ctx, token); authErr != nil {
+		return body, fmt.Errorf("re-authentication failed: %w (original error: %v)", authErr, err)
+	}
+
+	return c.doRequest(ctx, method, url, postbody)
+}
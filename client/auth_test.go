@@ -0,0 +1,80 @@
+package cloudcontrol_test
+
+import (
+	"context"
+	"errors"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+// expiringHandlers only accept the token handed out by sessionMock.
+func expiringHandlers(logins *int32) map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		types.UrlPathLogin: func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(logins, 1)
+			sessionMock(w, r)
+		},
+		types.UrlPathGroups: func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-User-Authorization") != "token12345" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"message":"Token expires","code":4100}`))
+				return
+			}
+			groupsMock(w, r)
+		},
+	}
+}
+
+func TestReauthentication_RetriesWithNewToken(t *testing.T) {
+	var logins int32
+	var refreshed types.Session
+	server := newTestServer(t, expiringHandlers(&logins))
+
+	client := cloudcontrol.NewClientWithUrl(server.URL,
+		cloudcontrol.WithCredentials(cloudcontrol.StaticCredentials{Username: "user", Password: "pass"}),
+		cloudcontrol.WithSessionHook(func(session types.Session) { refreshed = session }),
+	)
+	client.Utoken = "expired"
+
+	groups, err := client.GetGroups()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "My House", groups.Groups[0].GroupName)
+	assert.Equal(t, int32(1), logins)
+	assert.Equal(t, "token12345", client.Utoken)
+	assert.Equal(t, "token12345", refreshed.Utoken)
+}
+
+func TestReauthentication_WithoutCredentials(t *testing.T) {
+	var logins int32
+	server := newTestServer(t, expiringHandlers(&logins))
+
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+	client.Utoken = "expired"
+
+	_, err := client.GetGroups()
+
+	assert.ErrorIs(t, err, cloudcontrol.ErrUnauthorized)
+	assert.Equal(t, int32(0), logins)
+}
+
+func TestReauthentication_CredentialsError(t *testing.T) {
+	var logins int32
+	server := newTestServer(t, expiringHandlers(&logins))
+	providerErr := errors.New("vault unavailable")
+
+	client := cloudcontrol.NewClientWithUrl(server.URL,
+		cloudcontrol.WithCredentials(cloudcontrol.CredentialsFunc(func(ctx context.Context) (string, string, error) {
+			return "", "", providerErr
+		})),
+	)
+
+	_, err := client.GetGroups()
+
+	assert.ErrorIs(t, err, providerErr)
+	assert.Equal(t, int32(0), logins)
+}
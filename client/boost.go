@@ -0,0 +1,199 @@
+package cloudcontrol
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jesper-nord/go-pcc/types"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Boost is a temporary override of a device, reverted to Previous once it expires.
+type Boost struct {
+	DeviceGUID string         `json:"deviceGuid"`
+	Expires    time.Time      `json:"expires"`
+	Previous   types.Snapshot `json:"previous"`
+}
+
+// Expired reports whether the boost should have been reverted at the given time.
+func (b Boost) Expired(now time.Time) bool {
+	return !now.Before(b.Expires)
+}
+
+// BoostStore persists pending boosts in a JSON file, so that they can be reverted
+// by a later process. It is safe for concurrent use within one process.
+type BoostStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewBoostStore returns a BoostStore keeping pending boosts in the file at path.
+func NewBoostStore(path string) *BoostStore {
+	return &BoostStore{path: path}
+}
+
+// Path returns the path of the file holding the pending boosts.
+func (s *BoostStore) Path() string {
+	return s.path
+}
+
+// List returns all pending boosts, ordered by expiry.
+func (s *BoostStore) List() ([]Boost, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	boosts, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Boost, 0, len(boosts))
+	for _, boost := range boosts {
+		list = append(list, boost)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Expires.Before(list[j].Expires) })
+	return list, nil
+}
+
+// Get returns the pending boost of a device, if any.
+func (s *BoostStore) Get(guid string) (Boost, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	boosts, err := s.load()
+	if err != nil {
+		return Boost{}, false, err
+	}
+	boost, ok := boosts[guid]
+	return boost, ok, nil
+}
+
+// Put stores the boost, replacing any pending boost of the same device.
+func (s *BoostStore) Put(boost Boost) error {
+	return s.update(func(boosts map[string]Boost) {
+		boosts[boost.DeviceGUID] = boost
+	})
+}
+
+// Delete removes the pending boost of a device.
+func (s *BoostStore) Delete(guid string) error {
+	return s.update(func(boosts map[string]Boost) {
+		delete(boosts, guid)
+	})
+}
+
+func (s *BoostStore) update(fn func(map[string]Boost)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	boosts, err := s.load()
+	if err != nil {
+		return err
+	}
+	fn(boosts)
+	if len(boosts) == 0 {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, _ := json.MarshalIndent(boosts, "", "  ")
+	return writeTokenFile(s.path, data)
+}
+
+func (s *BoostStore) load() (map[string]Boost, error) {
+	boosts := map[string]Boost{}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return boosts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &boosts); err != nil {
+		return nil, fmt.Errorf("invalid boost file: %w", err)
+	}
+	return boosts, nil
+}
+
+// Boost sends the command and records a boost that reverts the device to its current
+// state after the duration. If the device already has a pending boost in the store,
+// the boost is extended and still reverts to the state from before the first one.
+//
+// The revert itself is left to the caller, see RevertExpiredBoosts and CancelBoost.
+// A nil store only returns the boost without persisting it.
+func (b *CommandBuilder) Boost(ctx context.Context, duration time.Duration, store *BoostStore) (Boost, error) {
+	var previous types.Snapshot
+	pending := false
+	if store != nil {
+		boost, ok, err := store.Get(b.device.guid)
+		if err != nil {
+			return Boost{}, err
+		}
+		previous, pending = boost.Previous, ok
+	}
+	if !pending {
+		snapshot, err := b.device.Snapshot(ctx)
+		if err != nil {
+			return Boost{}, err
+		}
+		previous = snapshot
+	}
+
+	if _, err := b.Send(ctx); err != nil {
+		return Boost{}, err
+	}
+
+	boost := Boost{DeviceGUID: b.device.guid, Expires: time.Now().Add(duration), Previous: previous}
+	if store != nil {
+		if err := store.Put(boost); err != nil {
+			return boost, fmt.Errorf("boost applied but not saved: %w", err)
+		}
+	}
+	return boost, nil
+}
+
+// CancelBoost reverts the pending boost of the device right away.
+// It reports false if the device had no pending boost.
+func (d *Device) CancelBoost(ctx context.Context, store *BoostStore) (bool, error) {
+	boost, ok, err := store.Get(d.guid)
+	if err != nil || !ok {
+		return false, err
+	}
+	if _, err := d.Restore(ctx, boost.Previous); err != nil {
+		return false, err
+	}
+	return true, store.Delete(d.guid)
+}
+
+// RevertExpiredBoosts restores every device whose boost in the store has expired and
+// returns the reverted boosts. Boosts that fail to revert are kept for a later attempt.
+func (c *Client) RevertExpiredBoosts(ctx context.Context, store *BoostStore) ([]Boost, error) {
+	boosts, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Boost
+	var errs []error
+	now := time.Now()
+	for _, boost := range boosts {
+		if !boost.Expired(now) {
+			continue
+		}
+		if _, err := c.Device(boost.DeviceGUID).Restore(ctx, boost.Previous); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", boost.DeviceGUID, err))
+			continue
+		}
+		if err := store.Delete(boost.DeviceGUID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		reverted = append(reverted, boost)
+	}
+	return reverted, errors.Join(errs...)
+}
@@ -0,0 +1,113 @@
+package cloudcontrol_test
+
+import (
+	"context"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// boostHandlers report a device switched off in heat mode at 19 degrees and record control commands.
+func boostHandlers(recorder *commandRecorder, statusCalls *int32) map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		types.UrlPathGroups:  groupsMock,
+		types.UrlPathControl: recorder.ServeHTTP,
+		types.UrlPathDeviceStatus: func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(statusCalls, 1)
+			_, _ = w.Write([]byte(`{"parameters":{"operate":0,"operationMode":3,"temperatureSet":19,"ecoMode":0}}`))
+		},
+	}
+}
+
+func TestBoost_RevertsWhenExpired(t *testing.T) {
+	recorder := &commandRecorder{}
+	var statusCalls int32
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, boostHandlers(recorder, &statusCalls)).URL)
+	store := cloudcontrol.NewBoostStore(filepath.Join(t.TempDir(), "boosts"))
+	device := client.Device("CZ-CAPWFC1+B8B7F1B3E326")
+
+	boost, err := device.Control().On().Temperature(24).Boost(context.Background(), 0, store)
+	assert.NoError(t, err)
+	assert.Equal(t, types.PowerOff, boost.Previous.Power)
+
+	// a later process finds the expired boost in the store
+	reverted, err := client.RevertExpiredBoosts(context.Background(), cloudcontrol.NewBoostStore(store.Path()))
+	assert.NoError(t, err)
+	assert.Len(t, reverted, 1)
+	assert.Len(t, recorder.commands, 2)
+	assert.Equal(t, 24.0, *recorder.commands[0].Parameters.TemperatureSet)
+	assert.Equal(t, int64(types.PowerOff), *recorder.commands[1].Parameters.Operate)
+	assert.Equal(t, 19.0, *recorder.commands[1].Parameters.TemperatureSet)
+
+	pending, err := store.List()
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestBoost_ExtendKeepsOriginalState(t *testing.T) {
+	recorder := &commandRecorder{}
+	var statusCalls int32
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, boostHandlers(recorder, &statusCalls)).URL)
+	store := cloudcontrol.NewBoostStore(filepath.Join(t.TempDir(), "boosts"))
+	device := client.Device("CZ-CAPWFC1+B8B7F1B3E326")
+
+	_, err1 := device.Control().EcoMode(types.EcoModePowerful).Boost(context.Background(), time.Hour, store)
+	_, err2 := device.Control().EcoMode(types.EcoModePowerful).Boost(context.Background(), 2*time.Hour, store)
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&statusCalls))
+
+	reverted, err := client.RevertExpiredBoosts(context.Background(), store)
+	assert.NoError(t, err)
+	assert.Empty(t, reverted)
+
+	cancelled, err := device.CancelBoost(context.Background(), store)
+	assert.NoError(t, err)
+	assert.True(t, cancelled)
+	assert.Len(t, recorder.commands, 3)
+	assert.Equal(t, int64(types.EcoModeAuto), *recorder.commands[2].Parameters.EcoMode)
+
+	cancelled, err = device.CancelBoost(context.Background(), store)
+	assert.NoError(t, err)
+	assert.False(t, cancelled)
+}
+
+func TestBoost_RevertsWithoutHorizontalSwing(t *testing.T) {
+	recorder := &commandRecorder{}
+	var statusCalls int32
+	handlers := boostHandlers(recorder, &statusCalls)
+	handlers[types.UrlPathGroups] = groupsWithoutSwingLRMock
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, handlers).URL, cloudcontrol.WithValidation())
+	store := cloudcontrol.NewBoostStore(filepath.Join(t.TempDir(), "boosts"))
+	device := client.Device("CZ-CAPWFC1+B8B7F1B3E326")
+
+	boost, err := device.Control().On().Boost(context.Background(), 0, store)
+	assert.NoError(t, err)
+	assert.Nil(t, boost.Previous.HorizontalSwing)
+
+	reverted, err := client.RevertExpiredBoosts(context.Background(), store)
+	assert.NoError(t, err)
+	assert.Len(t, reverted, 1)
+	assert.Len(t, recorder.commands, 2)
+	assert.Nil(t, recorder.commands[1].Parameters.AirSwingLR)
+	assert.Equal(t, int64(types.AutoSwingVertical), *recorder.commands[1].Parameters.FanAutoMode)
+}
+
+func TestBoostStore_UnknownEnumValues(t *testing.T) {
+	store := cloudcontrol.NewBoostStore(filepath.Join(t.TempDir(), "boosts"))
+	horizontal := types.HorizontalSwing(3)
+	boost := cloudcontrol.Boost{DeviceGUID: "living", Expires: time.Now().UTC().Truncate(time.Second),
+		Previous: types.Snapshot{DeviceGUID: "living", VerticalSwing: 7, HorizontalSwing: &horizontal}}
+
+	assert.NoError(t, store.Put(boost))
+	loaded, ok, err := cloudcontrol.NewBoostStore(store.Path()).Get("living")
+
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, boost.Previous, loaded.Previous)
+}
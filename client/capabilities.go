@@ -0,0 +1,85 @@
+package cloudcontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jesper-nord/go-pcc/types"
+)
+
+var (
+	// ErrUnsupported is returned when a command uses a feature the device does not have.
+	ErrUnsupported = errors.New("not supported by device")
+	// ErrDeviceNotFound is returned when a device is not part of the account.
+	ErrDeviceNotFound = errors.New("device not found")
+)
+
+// Info returns the device as listed by GetGroups, including its capabilities.
+// The device list is cached by the client after the first call to GetGroups.
+func (d *Device) Info(ctx context.Context) (types.Device, error) {
+	d.client.lock().RLock()
+	info, ok := d.client.devices[d.guid]
+	cached := d.client.devices != nil
+	d.client.lock().RUnlock()
+	if ok {
+		return info, nil
+	}
+
+	if !cached {
+		if _, err := d.client.GetGroupsContext(ctx); err != nil {
+			return types.Device{}, err
+		}
+		d.client.lock().RLock()
+		info, ok = d.client.devices[d.guid]
+		d.client.lock().RUnlock()
+		if ok {
+			return info, nil
+		}
+	}
+
+	return types.Device{}, fmt.Errorf("%w: %s", ErrDeviceNotFound, d.guid)
+}
+
+// cacheDevices remembers the devices of the account, used to look up capabilities.
+func (c *Client) cacheDevices(groups types.Groups) {
+	devices := map[string]types.Device{}
+	for _, group := range groups.Groups {
+		for _, device := range group.Devices {
+			devices[device.DeviceGUID] = device
+		}
+	}
+
+	c.lock().Lock()
+	defer c.lock().Unlock()
+	c.devices = devices
+}
+
+// feature is an optional device feature that must be advertised before it is controlled.
+type feature struct {
+	name      string
+	available func(types.Capabilities) bool
+}
+
+var (
+	featureNanoe   = feature{"nanoe", func(c types.Capabilities) bool { return c.Nanoe }}
+	featureEcoNavi = feature{"ecoNavi", func(c types.Capabilities) bool { return c.EcoNavi }}
+	featureIAutoX  = feature{"iAuto-X", func(c types.Capabilities) bool { return c.IAutoX }}
+)
+
+// checkFeatures returns an error wrapping ErrUnsupported if the device lacks any of the features.
+func (d *Device) checkFeatures(ctx context.Context, features []feature) error {
+	if len(features) == 0 {
+		return nil
+	}
+	info, err := d.Info(ctx)
+	if err != nil {
+		return err
+	}
+	capabilities := info.Capabilities()
+	for _, f := range features {
+		if !f.available(capabilities) {
+			return fmt.Errorf("%s: %w", f.name, ErrUnsupported)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package cloudcontrol_test
+
+import (
+	"context"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// capabilityHandlers serve the groups fixture, a status in heat mode and record control commands.
+func capabilityHandlers(recorder *commandRecorder) map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		types.UrlPathGroups:  groupsMock,
+		types.UrlPathControl: recorder.ServeHTTP,
+		types.UrlPathDeviceStatus: func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"parameters":{"operate":1,"operationMode":3,"temperatureSet":21}}`))
+		},
+	}
+}
+
+func TestSetNanoe_SupportedDevice(t *testing.T) {
+	recorder := &commandRecorder{}
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, capabilityHandlers(recorder)).URL)
+
+	_, err := client.Device("CZ-CAPWFC1+B8B7F1B3E326").SetNanoe(context.Background(), types.NanoeOn)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(types.NanoeOn), *recorder.commands[0].Parameters.Nanoe)
+}
+
+func TestSetEcoNavi_UnsupportedDevice(t *testing.T) {
+	recorder := &commandRecorder{}
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, capabilityHandlers(recorder)).URL)
+
+	_, err1 := client.Device("CZ-CAPWFC1+B8B7F1B3E326").SetEcoNavi(context.Background(), types.FeatureOn)
+	_, err2 := client.Device("CZ-CAPWFC1+B8B7F1B3E326").SetIAuto(context.Background(), types.FeatureOff)
+
+	assert.ErrorIs(t, err1, cloudcontrol.ErrUnsupported)
+	assert.ErrorIs(t, err2, cloudcontrol.ErrUnsupported)
+	assert.Empty(t, recorder.commands)
+}
+
+func TestDeviceInfo_UnknownDevice(t *testing.T) {
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, capabilityHandlers(&commandRecorder{})).URL)
+
+	_, err := client.Device("unknown").Info(context.Background())
+
+	assert.ErrorIs(t, err, cloudcontrol.ErrDeviceNotFound)
+}
+
+func TestCommandBuilder_RejectsUnavailable(t *testing.T) {
+	recorder := &commandRecorder{}
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, capabilityHandlers(recorder)).URL)
+	device := client.Device("CZ-CAPWFC1+B8B7F1B3E326")
+
+	_, nanoeErr := device.Control().On().Nanoe(types.NanoeUnavailable).Send(context.Background())
+	_, ecoNaviErr := device.Control().EcoNavi(types.FeatureUnavailable).Send(context.Background())
+	_, iAutoErr := device.SetIAuto(context.Background(), types.FeatureUnavailable)
+
+	assert.ErrorIs(t, nanoeErr, cloudcontrol.ErrInvalidCommand)
+	assert.ErrorIs(t, ecoNaviErr, cloudcontrol.ErrInvalidCommand)
+	assert.ErrorIs(t, iAutoErr, cloudcontrol.ErrInvalidCommand)
+	assert.Empty(t, recorder.commands)
+}
@@ -2,37 +2,108 @@ package cloudcontrol
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"github.com/jesper-nord/go-pcc/types"
 	"github.com/labstack/gommon/log"
 	"io"
 	"net/http"
-	"net/url"
-	"time"
+	"sync"
 )
 
 // Client is a Panasonic Comfort Cloud client.
+//
+// A Client created with NewClient is safe for concurrent use by multiple goroutines, as long
+// as its fields are not accessed directly. Server must not be changed once the client is in use.
 type Client struct {
-	Utoken     string
+	// Utoken is the session token.
+	//
+	// Deprecated: use Token and SetToken, which are safe for concurrent use.
+	Utoken string
+	// DeviceGUID is the device controlled by the device methods of the client.
+	//
+	// Deprecated: use SetDevice, or Device to control several devices.
 	DeviceGUID string
 	Server     string
+
+	// mu guards Utoken, DeviceGUID and devices. The mutexes are pointers, as NewClient
+	// returns the client by value.
+	mu      *sync.RWMutex
+	devices map[string]types.Device
+
+	// authMu serialises re-authentication so that concurrent requests
+	// rejected with the same expired token only log in once.
+	authMu *sync.Mutex
+
+	concurrency int
+	httpClient  *http.Client
+	userAgent   string
+	appVersion  string
+	credentials CredentialsProvider
+	sessionHook func(types.Session)
+	tokenStore  TokenStore
+	retryPolicy RetryPolicy
+	rateLimiter *RateLimiter
+	validate    bool
+}
+
+// lock returns the mutex guarding the client state, created on first use by clients
+// not created with NewClient.
+func (c *Client) lock() *sync.RWMutex {
+	if c.mu == nil {
+		c.mu = &sync.RWMutex{}
+	}
+	return c.mu
 }
 
-// SetDevice sets the device GUID on the client.
+// SetDevice sets the device GUID used by the device methods of the client.
+// Use Device to control several devices with the same client.
 func (c *Client) SetDevice(deviceGUID string) {
+	c.lock().Lock()
+	defer c.lock().Unlock()
 	c.DeviceGUID = deviceGUID
 }
 
+// deviceGUID returns the device GUID set with SetDevice.
+func (c *Client) deviceGUID() string {
+	c.lock().RLock()
+	defer c.lock().RUnlock()
+	return c.DeviceGUID
+}
+
+// Token returns the current session token.
+func (c *Client) Token() string {
+	c.lock().RLock()
+	defer c.lock().RUnlock()
+	return c.Utoken
+}
+
+// SetToken sets the session token, e.g. one restored from an earlier session.
+func (c *Client) SetToken(token string) {
+	c.lock().Lock()
+	defer c.lock().Unlock()
+	c.Utoken = token
+}
+
 // NewClient creates a new Panasonic Comfort Cloud client.
-func NewClient() Client {
-	return NewClientWithUrl(types.BaseServerUrl)
+func NewClient(opts ...Option) Client {
+	return NewClientWithUrl(types.BaseServerUrl, opts...)
 }
 
 // NewClientWithUrl creates a new client with given base URL.
-func NewClientWithUrl(url string) Client {
-	client := Client{}
-	client.Server = url
+func NewClientWithUrl(url string, opts ...Option) Client {
+	client := Client{
+		Server:     url,
+		mu:         &sync.RWMutex{},
+		authMu:     &sync.Mutex{},
+		httpClient: newHTTPClient(),
+		userAgent:  types.DefaultUserAgent,
+		appVersion: types.DefaultAppVersion,
+	}
+	for _, opt := range opts {
+		opt(&client)
+	}
+	client.loadSession()
 
 	log.Debugf("Created new client for %s", client.Server)
 
@@ -41,10 +112,15 @@ func NewClientWithUrl(url string) Client {
 
 // ValidateSession checks if the session token is still valid.
 func (c *Client) ValidateSession(token string) ([]byte, error) {
-	c.Utoken = token
-	body, err := c.doGetRequest(types.UrlPathValidate)
+	return c.ValidateSessionContext(context.Background(), token)
+}
+
+// ValidateSessionContext checks if the session token is still valid, using the provided context.
+func (c *Client) ValidateSessionContext(ctx context.Context, token string) ([]byte, error) {
+	c.SetToken(token)
+	body, err := c.doGetRequest(ctx, types.UrlPathValidate)
 	if err != nil {
-		return body, fmt.Errorf("error: %v %s", err, body)
+		return body, err
 	}
 
 	return body, nil
@@ -52,47 +128,65 @@ func (c *Client) ValidateSession(token string) ([]byte, error) {
 
 // CreateSession initialises a client session to Panasonic Comfort Cloud.
 func (c *Client) CreateSession(username string, password string) ([]byte, error) {
+	return c.CreateSessionContext(context.Background(), username, password)
+}
+
+// CreateSessionContext initialises a client session to Panasonic Comfort Cloud, using the provided context.
+func (c *Client) CreateSessionContext(ctx context.Context, username string, password string) ([]byte, error) {
 	postBody, _ := json.Marshal(map[string]any{
 		"language": 0,
 		"loginId":  username,
 		"password": password,
 	})
 
-	body, err := c.doPostRequest(types.UrlPathLogin, postBody)
+	body, err := c.doPostRequest(ctx, types.UrlPathLogin, postBody)
 	if err != nil {
-		return nil, fmt.Errorf("error: %v %s", err, body)
+		return nil, err
 	}
 
 	session := types.Session{}
-	err = json.Unmarshal(body, &session)
-	if err != nil {
-		log.Fatalf("unmarshal error %v: %s", err, body)
+	if err := decode(body, &session); err != nil {
+		return body, err
 	}
 
-	c.Utoken = session.Utoken
+	c.SetToken(session.Utoken)
+	c.saveSession(session)
+	if c.sessionHook != nil {
+		c.sessionHook(session)
+	}
 
 	return body, nil
 }
 
 // GetGroups gets all Panasonic Comfort Cloud groups associated to this account.
 func (c *Client) GetGroups() (types.Groups, error) {
-	body, err := c.doGetRequest(types.UrlPathGroups)
+	return c.GetGroupsContext(context.Background())
+}
+
+// GetGroupsContext gets all groups associated to this account, using the provided context.
+func (c *Client) GetGroupsContext(ctx context.Context) (types.Groups, error) {
+	body, err := c.doGetRequest(ctx, types.UrlPathGroups)
 	if err != nil {
-		return types.Groups{}, fmt.Errorf("error: %v %s", err, body)
+		return types.Groups{}, err
 	}
 	groups := types.Groups{}
-	err = json.Unmarshal(body, &groups)
-	if err != nil {
-		log.Fatalf("unmarshal error %v: %s", err, body)
+	if err := decode(body, &groups); err != nil {
+		return types.Groups{}, err
 	}
+	c.cacheDevices(groups)
 
 	return groups, nil
 }
 
 // ListDevices lists all available devices.
 func (c *Client) ListDevices() ([]string, error) {
+	return c.ListDevicesContext(context.Background())
+}
+
+// ListDevicesContext lists all available devices, using the provided context.
+func (c *Client) ListDevicesContext(ctx context.Context) ([]string, error) {
 	var available []string
-	groups, err := c.GetGroups()
+	groups, err := c.GetGroupsContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -104,198 +198,257 @@ func (c *Client) ListDevices() ([]string, error) {
 	return available, nil
 }
 
-// GetDeviceStatus gets all details for a specific device.
-func (c *Client) GetDeviceStatus() (types.Device, error) {
-	body, err := c.doGetRequest(types.UrlPathDeviceStatus + url.QueryEscape(c.DeviceGUID))
-	if err != nil {
-		return types.Device{}, fmt.Errorf("error: %v %s", err, body)
-	}
+// DeviceSummaries lists all available devices with their group and current state.
+func (c *Client) DeviceSummaries() ([]types.DeviceSummary, error) {
+	return c.DeviceSummariesContext(context.Background())
+}
 
-	device := types.Device{}
-	err = json.Unmarshal(body, &device)
+// DeviceSummariesContext lists all available devices with their group and current state, using the provided context.
+func (c *Client) DeviceSummariesContext(ctx context.Context) ([]types.DeviceSummary, error) {
+	groups, err := c.GetGroupsContext(ctx)
 	if err != nil {
-		log.Fatalf("unmarshal error %v: %s", err, body)
+		return nil, err
 	}
-
-	return device, nil
+	return groups.Summaries(), nil
 }
 
-// GetDeviceHistory will fetch historical device data from Panasonic.
-func (c *Client) GetDeviceHistory(timeFrame int64) (types.History, error) {
-	postBody, _ := json.Marshal(map[string]string{
-		"dataMode":   fmt.Sprint(timeFrame),
-		"date":       time.Now().Format("20060102"),
-		"deviceGuid": c.DeviceGUID,
-		"osTimezone": "+01:00",
-	})
+// GetDeviceStatus gets all details for a specific device.
+func (c *Client) GetDeviceStatus() (types.Device, error) {
+	return c.GetDeviceStatusContext(context.Background())
+}
 
-	body, err := c.doPostRequest(types.UrlPathHistory, postBody)
-	if err != nil {
-		return types.History{}, fmt.Errorf("error: %v %s", err, body)
-	}
+// GetDeviceStatusContext gets all details for a specific device, using the provided context.
+func (c *Client) GetDeviceStatusContext(ctx context.Context) (types.Device, error) {
+	return c.Device(c.deviceGUID()).Status(ctx)
+}
 
-	history := types.History{}
-	err = json.Unmarshal(body, &history)
-	if err != nil {
-		log.Fatalf("unmarshal error %v: %s", err, body)
-	}
+// GetDeviceHistory will fetch historical device data from Panasonic.
+func (c *Client) GetDeviceHistory(period types.HistoryPeriod) (types.History, error) {
+	return c.GetDeviceHistoryContext(context.Background(), period)
+}
 
-	return history, nil
+// GetDeviceHistoryContext will fetch historical device data from Panasonic, using the provided context.
+func (c *Client) GetDeviceHistoryContext(ctx context.Context, period types.HistoryPeriod) (types.History, error) {
+	return c.Device(c.deviceGUID()).History(ctx, period)
 }
 
 // SetTemperature will set the temperature for a device.
 func (c *Client) SetTemperature(temperature float64) ([]byte, error) {
-	command := types.Command{
-		DeviceGUID: c.DeviceGUID,
-		Parameters: types.DeviceControlParameters{
-			TemperatureSet: &temperature,
-		},
-	}
+	return c.SetTemperatureContext(context.Background(), temperature)
+}
 
-	return c.control(command)
+// SetTemperatureContext will set the temperature for a device, using the provided context.
+func (c *Client) SetTemperatureContext(ctx context.Context, temperature float64) ([]byte, error) {
+	return c.Device(c.deviceGUID()).SetTemperature(ctx, temperature)
 }
 
 // SetFanSpeed will set the fan speed for a device.
-func (c *Client) SetFanSpeed(fanSpeed int64) ([]byte, error) {
-	command := types.Command{
-		DeviceGUID: c.DeviceGUID,
-		Parameters: types.DeviceControlParameters{
-			FanSpeed: &fanSpeed,
-		},
-	}
+func (c *Client) SetFanSpeed(fanSpeed types.FanSpeed) ([]byte, error) {
+	return c.SetFanSpeedContext(context.Background(), fanSpeed)
+}
 
-	return c.control(command)
+// SetFanSpeedContext will set the fan speed for a device, using the provided context.
+func (c *Client) SetFanSpeedContext(ctx context.Context, fanSpeed types.FanSpeed) ([]byte, error) {
+	return c.Device(c.deviceGUID()).SetFanSpeed(ctx, fanSpeed)
 }
 
 // TurnOn will switch the device on.
 func (c *Client) TurnOn() ([]byte, error) {
-	var on int64 = 1
-	command := types.Command{
-		DeviceGUID: c.DeviceGUID,
-		Parameters: types.DeviceControlParameters{
-			Operate: &on,
-		},
-	}
+	return c.TurnOnContext(context.Background())
+}
 
-	return c.control(command)
+// TurnOnContext will switch the device on, using the provided context.
+func (c *Client) TurnOnContext(ctx context.Context) ([]byte, error) {
+	return c.Device(c.deviceGUID()).TurnOn(ctx)
 }
 
 // TurnOff will switch the device off.
 func (c *Client) TurnOff() ([]byte, error) {
-	var off int64 = 0
-	command := types.Command{
-		DeviceGUID: c.DeviceGUID,
-		Parameters: types.DeviceControlParameters{
-			Operate: &off,
-		},
-	}
+	return c.TurnOffContext(context.Background())
+}
 
-	return c.control(command)
+// TurnOffContext will switch the device off, using the provided context.
+func (c *Client) TurnOffContext(ctx context.Context) ([]byte, error) {
+	return c.Device(c.deviceGUID()).TurnOff(ctx)
 }
 
 // SetMode will set the device to the requested AC mode.
-func (c *Client) SetMode(mode int64) ([]byte, error) {
-	command := types.Command{
-		DeviceGUID: c.DeviceGUID,
-		Parameters: types.DeviceControlParameters{},
-	}
-
-	command.Parameters.OperationMode = &mode
+func (c *Client) SetMode(mode types.OperationMode) ([]byte, error) {
+	return c.SetModeContext(context.Background(), mode)
+}
 
-	return c.control(command)
+// SetModeContext will set the device to the requested AC mode, using the provided context.
+func (c *Client) SetModeContext(ctx context.Context, mode types.OperationMode) ([]byte, error) {
+	return c.Device(c.deviceGUID()).SetMode(ctx, mode)
 }
 
 // SetEcoMode will set the device to the requested eco mode.
-func (c *Client) SetEcoMode(mode int64) ([]byte, error) {
-	command := types.Command{
-		DeviceGUID: c.DeviceGUID,
-		Parameters: types.DeviceControlParameters{},
-	}
+func (c *Client) SetEcoMode(mode types.EcoMode) ([]byte, error) {
+	return c.SetEcoModeContext(context.Background(), mode)
+}
+
+// SetEcoModeContext will set the device to the requested eco mode, using the provided context.
+func (c *Client) SetEcoModeContext(ctx context.Context, mode types.EcoMode) ([]byte, error) {
+	return c.Device(c.deviceGUID()).SetEcoMode(ctx, mode)
+}
+
+// SetAirSwing will set the louvre positions for a device.
+func (c *Client) SetAirSwing(vertical types.VerticalSwing, horizontal types.HorizontalSwing) ([]byte, error) {
+	return c.SetAirSwingContext(context.Background(), vertical, horizontal)
+}
 
-	command.Parameters.EcoMode = &mode
+// SetAirSwingContext will set the louvre positions for a device, using the provided context.
+func (c *Client) SetAirSwingContext(ctx context.Context, vertical types.VerticalSwing, horizontal types.HorizontalSwing) ([]byte, error) {
+	return c.Device(c.deviceGUID()).SetAirSwing(ctx, vertical, horizontal)
+}
+
+// SetNanoe will set the nanoe mode for a device.
+func (c *Client) SetNanoe(mode types.NanoeMode) ([]byte, error) {
+	return c.SetNanoeContext(context.Background(), mode)
+}
+
+// SetNanoeContext will set the nanoe mode for a device, using the provided context.
+func (c *Client) SetNanoeContext(ctx context.Context, mode types.NanoeMode) ([]byte, error) {
+	return c.Device(c.deviceGUID()).SetNanoe(ctx, mode)
+}
+
+// SetEcoNavi will switch ecoNavi on or off for a device.
+func (c *Client) SetEcoNavi(state types.FeatureState) ([]byte, error) {
+	return c.SetEcoNaviContext(context.Background(), state)
+}
 
-	return c.control(command)
+// SetEcoNaviContext will switch ecoNavi on or off for a device, using the provided context.
+func (c *Client) SetEcoNaviContext(ctx context.Context, state types.FeatureState) ([]byte, error) {
+	return c.Device(c.deviceGUID()).SetEcoNavi(ctx, state)
+}
+
+// SetIAuto will switch iAuto-X on or off for a device.
+func (c *Client) SetIAuto(state types.FeatureState) ([]byte, error) {
+	return c.SetIAutoContext(context.Background(), state)
+}
+
+// SetIAutoContext will switch iAuto-X on or off for a device, using the provided context.
+func (c *Client) SetIAutoContext(ctx context.Context, state types.FeatureState) ([]byte, error) {
+	return c.Device(c.deviceGUID()).SetIAuto(ctx, state)
+}
+
+// Snapshot captures the controllable state of a device.
+func (c *Client) Snapshot() (types.Snapshot, error) {
+	return c.SnapshotContext(context.Background())
+}
+
+// SnapshotContext captures the controllable state of a device, using the provided context.
+func (c *Client) SnapshotContext(ctx context.Context) (types.Snapshot, error) {
+	return c.Device(c.deviceGUID()).Snapshot(ctx)
+}
+
+// Restore will put a device back into the state captured by Snapshot.
+func (c *Client) Restore(snapshot types.Snapshot) ([]byte, error) {
+	return c.RestoreContext(context.Background(), snapshot)
+}
+
+// RestoreContext will put a device back into the state captured by Snapshot, using the provided context.
+func (c *Client) RestoreContext(ctx context.Context, snapshot types.Snapshot) ([]byte, error) {
+	return c.Device(c.deviceGUID()).Restore(ctx, snapshot)
 }
 
 // control sends commands to the Panasonic cloud to control a device.
-func (c *Client) control(command types.Command) ([]byte, error) {
+func (c *Client) control(ctx context.Context, command types.Command) ([]byte, error) {
 	postBody, _ := json.Marshal(command)
 
 	log.Debugf("Command: %s", postBody)
 
-	body, err := c.doPostRequest(types.UrlPathControl, postBody)
+	body, err := c.doPostRequest(ctx, types.UrlPathControl, postBody)
 	if err != nil {
-		return nil, fmt.Errorf("error: %v %s", err, body)
+		return nil, err
 	}
 	if string(body) != types.SuccessResponse {
-		return body, fmt.Errorf("error body: %v %s", err, body)
+		return body, newAPIError(http.StatusOK, body)
 	}
 
 	return body, nil
 }
 
-func (c *Client) doPostRequest(url string, postbody []byte) ([]byte, error) {
-	req, err := http.NewRequest("POST", c.Server+url, bytes.NewBuffer(postbody))
-	c.setHeaders(req)
+func (c *Client) doPostRequest(ctx context.Context, url string, postbody []byte) ([]byte, error) {
+	return c.doAuthenticatedRequest(ctx, http.MethodPost, url, postbody)
+}
 
-	log.Debugf("POST request URL: %#v\n", req.URL)
-	log.Debugf("POST request body: %#v\n", string(postbody))
+func (c *Client) doGetRequest(ctx context.Context, url string) ([]byte, error) {
+	return c.doAuthenticatedRequest(ctx, http.MethodGet, url, nil)
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+func (c *Client) sendRequest(ctx context.Context, method string, url string, postbody []byte) ([]byte, error) {
+	var reqBody io.Reader
+	if postbody != nil {
+		reqBody = bytes.NewBuffer(postbody)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.Server+url, reqBody)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-
-	log.Debugf("POST response body: %s", string(body))
-
-	if resp.StatusCode > 200 {
-		return body, fmt.Errorf("HTTP Error: %s", resp.Status)
-	}
-
-	return body, nil
-}
-
-func (c *Client) doGetRequest(url string) ([]byte, error) {
-	req, err := http.NewRequest("GET", c.Server+url, nil)
 	c.setHeaders(req)
 
-	log.Debugf("GET request URL: %#v", req.URL)
+	log.Debugf("%s request URL: %#v", method, req.URL)
+	if postbody != nil {
+		log.Debugf("%s request body: %#v", method, string(postbody))
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.http().Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
 
-	log.Debugf("GET response body: %s", string(body))
+	log.Debugf("%s response body: %s", method, string(body))
 
 	if resp.StatusCode > 200 {
-		return body, fmt.Errorf("HTTP Error: %s", resp.Status)
+		apiErr := newAPIError(resp.StatusCode, body)
+		apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		return body, apiErr
 	}
 
 	return body, nil
 }
 
 func (c *Client) setHeaders(req *http.Request) {
-	if c.Utoken != "" {
-		req.Header.Set("X-User-Authorization", c.Utoken)
+	if token := c.Token(); token != "" {
+		req.Header.Set("X-User-Authorization", token)
 	}
 	req.Header.Set("X-APP-TYPE", "1")
-	req.Header.Set("X-APP-VERSION", "1.20.0")
+	req.Header.Set("X-APP-VERSION", c.appVersionHeader())
 	req.Header.Set("X-APP-TIMESTAMP", "1")
 	req.Header.Set("X-APP-NAME", "Comfort Cloud")
 	req.Header.Set("X-CFC-API-KEY", "Comfort Cloud")
-	req.Header.Set("User-Agent", "G-RAC")
+	req.Header.Set("User-Agent", c.userAgentHeader())
 	req.Header.Set("Accept", "application/json; charset=utf-8")
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Connection", "Keep-Alive")
 
 	log.Debugf("HTTP headers set to: %#v", req.Header)
 }
+
+func (c *Client) http() *http.Client {
+	if c.httpClient == nil {
+		return defaultHTTPClient
+	}
+	return c.httpClient
+}
+
+func (c *Client) userAgentHeader() string {
+	if c.userAgent == "" {
+		return types.DefaultUserAgent
+	}
+	return c.userAgent
+}
+
+func (c *Client) appVersionHeader() string {
+	if c.appVersion == "" {
+		return types.DefaultAppVersion
+	}
+	return c.appVersion
+}
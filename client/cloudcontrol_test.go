@@ -1,12 +1,14 @@
 package cloudcontrol_test
 
 import (
+	"context"
 	cloudcontrol "github.com/jesper-nord/go-pcc/client"
 	"github.com/jesper-nord/go-pcc/types"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -90,9 +92,19 @@ func TestGetGroups(t *testing.T) {
 	assert.Equal(t, 1, len(groups.Groups[0].Devices))
 }
 
+func TestDeviceSummaries(t *testing.T) {
+	client.CreateSession("", "")
+	summaries, err := client.DeviceSummaries()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(summaries))
+	assert.Equal(t, "My House", summaries[0].Group)
+	assert.Equal(t, types.PowerOn, summaries[0].Power)
+}
+
 func TestGetDeviceHistory(t *testing.T) {
 	client.CreateSession("", "")
-	history, err := client.GetDeviceHistory(types.HistoryDataMode["day"])
+	history, err := client.GetDeviceHistory(types.HistoryDay)
 	if err != nil {
 		t.Error(err)
 	}
@@ -115,6 +127,15 @@ func TestCreateSession(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestGetGroupsContext_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.GetGroupsContext(ctx)
+
+	assert.ErrorContains(t, err, context.Canceled.Error())
+}
+
 func serverMock() *httptest.Server {
 	handler := http.NewServeMux()
 	handler.HandleFunc(types.UrlPathLogin, sessionMock)
@@ -127,6 +148,17 @@ func serverMock() *httptest.Server {
 	return srv
 }
 
+// newTestServer serves the handlers, keyed by path pattern, until the test ends.
+func newTestServer(t *testing.T, handlers map[string]http.HandlerFunc) *httptest.Server {
+	mux := http.NewServeMux()
+	for pattern, handler := range handlers {
+		mux.HandleFunc(pattern, handler)
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
 func historyMock(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte(historyBody))
 }
@@ -139,6 +171,11 @@ func groupsMock(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte(groupsBody))
 }
 
+// groupsWithoutSwingLRMock serves the groups fixture for a device without horizontal louvres.
+func groupsWithoutSwingLRMock(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte(strings.Replace(groupsBody, `"airSwingLR":true`, `"airSwingLR":false`, 1)))
+}
+
 func controlMock(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte(controlBody))
 }
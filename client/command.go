@@ -0,0 +1,219 @@
+package cloudcontrol
+
+import (
+	"context"
+	"errors"
+	"github.com/jesper-nord/go-pcc/types"
+	"reflect"
+)
+
+// ErrEmptyCommand is returned when sending a command without any parameters.
+var ErrEmptyCommand = errors.New("command has no parameters")
+
+// CommandBuilder combines several parameter changes into a single control command,
+// so that they are sent to the device in one request.
+type CommandBuilder struct {
+	device     *Device
+	parameters types.DeviceControlParameters
+	vertical   *types.VerticalSwing
+	horizontal *types.HorizontalSwing
+	// noHorizontalSwing is set for devices without horizontal louvres, which then count as not swinging.
+	noHorizontalSwing bool
+	features          []feature
+	conditions        []Condition
+	// err is the first invalid value given, returned by Send.
+	err error
+}
+
+// Control starts a command for the device set with SetDevice.
+func (c *Client) Control() *CommandBuilder {
+	return c.Device(c.deviceGUID()).Control()
+}
+
+// Control starts a command for the device.
+func (d *Device) Control() *CommandBuilder {
+	return &CommandBuilder{device: d}
+}
+
+// On switches the device on.
+func (b *CommandBuilder) On() *CommandBuilder {
+	return b.Power(types.PowerOn)
+}
+
+// Off switches the device off.
+func (b *CommandBuilder) Off() *CommandBuilder {
+	return b.Power(types.PowerOff)
+}
+
+// Power switches the device on or off.
+func (b *CommandBuilder) Power(state types.PowerState) *CommandBuilder {
+	value := int64(state)
+	b.parameters.Operate = &value
+	return b
+}
+
+// Mode sets the AC mode.
+func (b *CommandBuilder) Mode(mode types.OperationMode) *CommandBuilder {
+	value := int64(mode)
+	b.parameters.OperationMode = &value
+	return b
+}
+
+// Temperature sets the temperature in Celsius.
+func (b *CommandBuilder) Temperature(temperature float64) *CommandBuilder {
+	b.parameters.TemperatureSet = &temperature
+	return b
+}
+
+// FanSpeed sets the fan speed.
+func (b *CommandBuilder) FanSpeed(fanSpeed types.FanSpeed) *CommandBuilder {
+	value := int64(fanSpeed)
+	b.parameters.FanSpeed = &value
+	return b
+}
+
+// EcoMode sets the eco mode.
+func (b *CommandBuilder) EcoMode(mode types.EcoMode) *CommandBuilder {
+	value := int64(mode)
+	b.parameters.EcoMode = &value
+	return b
+}
+
+// VerticalSwing sets the vertical louvre position, or auto swing.
+func (b *CommandBuilder) VerticalSwing(position types.VerticalSwing) *CommandBuilder {
+	b.vertical = &position
+	b.applySwing()
+	return b
+}
+
+// HorizontalSwing sets the horizontal louvre position, or auto swing.
+func (b *CommandBuilder) HorizontalSwing(position types.HorizontalSwing) *CommandBuilder {
+	b.horizontal = &position
+	b.applySwing()
+	return b
+}
+
+// applySwing derives fanAutoMode, airSwingUD and airSwingLR from the requested louvre positions.
+// A direction that has not been set is assumed not to swing automatically, until Send looks up
+// its current state with resolveSwing.
+func (b *CommandBuilder) applySwing() {
+	vertical := types.VerticalSwingMid
+	if b.vertical != nil {
+		vertical = *b.vertical
+	}
+	horizontal := types.HorizontalSwingMid
+	if b.horizontal != nil {
+		horizontal = *b.horizontal
+	}
+
+	mode := int64(types.NewAutoSwingMode(vertical, horizontal))
+	b.parameters.FanAutoMode = &mode
+
+	if b.vertical != nil && vertical != types.VerticalSwingAuto {
+		ud := int64(vertical)
+		b.parameters.AirSwingUD = &ud
+	}
+	if b.horizontal != nil && horizontal != types.HorizontalSwingAuto {
+		lr := int64(horizontal)
+		b.parameters.AirSwingLR = &lr
+	}
+}
+
+// resolveSwing keeps the auto swing of the direction that was not set, as fanAutoMode covers
+// both directions. The current state is read from the device status.
+func (b *CommandBuilder) resolveSwing(ctx context.Context) error {
+	if (b.vertical == nil) == (b.horizontal == nil) || (b.horizontal == nil && b.noHorizontalSwing) {
+		return nil
+	}
+
+	status, err := b.device.Status(ctx)
+	if err != nil {
+		return err
+	}
+	vertical, horizontal := status.Parameters.VerticalSwing(), status.Parameters.HorizontalSwing()
+	if b.vertical != nil {
+		vertical = *b.vertical
+	}
+	if b.horizontal != nil {
+		horizontal = *b.horizontal
+	}
+
+	mode := int64(types.NewAutoSwingMode(vertical, horizontal))
+	b.parameters.FanAutoMode = &mode
+	return nil
+}
+
+// Nanoe sets the nanoe mode. The device must support nanoe.
+func (b *CommandBuilder) Nanoe(mode types.NanoeMode) *CommandBuilder {
+	if mode == types.NanoeUnavailable {
+		return b.invalid(&ValidationError{"nanoe", mode, "cannot be set"})
+	}
+	value := int64(mode)
+	b.parameters.Nanoe = &value
+	b.features = append(b.features, featureNanoe)
+	return b
+}
+
+// EcoNavi switches ecoNavi on or off. The device must support ecoNavi.
+func (b *CommandBuilder) EcoNavi(state types.FeatureState) *CommandBuilder {
+	if state == types.FeatureUnavailable {
+		return b.invalid(&ValidationError{"ecoNavi", state, "cannot be set"})
+	}
+	value := int64(state)
+	b.parameters.EcoNavi = &value
+	b.features = append(b.features, featureEcoNavi)
+	return b
+}
+
+// IAuto switches iAuto-X on or off. The device must support iAuto-X.
+func (b *CommandBuilder) IAuto(state types.FeatureState) *CommandBuilder {
+	if state == types.FeatureUnavailable {
+		return b.invalid(&ValidationError{"iAuto", state, "cannot be set"})
+	}
+	value := int64(state)
+	b.parameters.Iauto = &value
+	b.features = append(b.features, featureIAutoX)
+	return b
+}
+
+// invalid records the first invalid value given, so that Send fails.
+func (b *CommandBuilder) invalid(err error) *CommandBuilder {
+	if b.err == nil {
+		b.err = err
+	}
+	return b
+}
+
+// Parameters returns the parameters collected so far.
+func (b *CommandBuilder) Parameters() types.DeviceControlParameters {
+	return b.parameters
+}
+
+// Empty reports whether no parameter has been set.
+func (b *CommandBuilder) Empty() bool {
+	return reflect.ValueOf(b.parameters).IsZero()
+}
+
+// Send sends all collected parameters to the device in one request. It returns a
+// ValidationError if a value was given that cannot be set, such as NanoeUnavailable.
+func (b *CommandBuilder) Send(ctx context.Context) ([]byte, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if b.Empty() {
+		return nil, ErrEmptyCommand
+	}
+	if err := b.device.checkFeatures(ctx, b.features); err != nil {
+		return nil, err
+	}
+	if err := b.resolveSwing(ctx); err != nil {
+		return nil, err
+	}
+	if err := b.device.validate(ctx, b.parameters); err != nil {
+		return nil, err
+	}
+	if err := b.device.checkConditions(ctx, b.conditions); err != nil {
+		return nil, err
+	}
+	return b.device.control(ctx, b.parameters)
+}
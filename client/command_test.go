@@ -0,0 +1,93 @@
+package cloudcontrol_test
+
+import (
+	"context"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCommandBuilder_SendsOneRequest(t *testing.T) {
+	recorder := &commandRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+
+	_, err := client.Device("living").Control().On().Mode(types.ModeHeat).Temperature(21).FanSpeed(types.FanSpeedMid).EcoMode(types.EcoModeQuiet).Send(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(recorder.commands))
+	parameters := recorder.commands[0].Parameters
+	assert.Equal(t, "living", recorder.commands[0].DeviceGUID)
+	assert.Equal(t, int64(1), *parameters.Operate)
+	assert.Equal(t, int64(3), *parameters.OperationMode)
+	assert.Equal(t, 21.0, *parameters.TemperatureSet)
+	assert.Equal(t, int64(3), *parameters.FanSpeed)
+	assert.Equal(t, int64(2), *parameters.EcoMode)
+	assert.Nil(t, parameters.AirSwingUD)
+}
+
+func TestCommandBuilder_Empty(t *testing.T) {
+	client := cloudcontrol.NewClientWithUrl("http://pcc.invalid")
+
+	command := client.Device("living").Control()
+	_, err := command.Send(context.Background())
+
+	assert.True(t, command.Empty())
+	assert.ErrorIs(t, err, cloudcontrol.ErrEmptyCommand)
+	assert.False(t, command.Off().Empty())
+}
+
+func TestCommandBuilder_AirSwing(t *testing.T) {
+	recorder := &commandRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+
+	_, err := client.Device("living").SetAirSwing(context.Background(), types.VerticalSwingAuto, types.HorizontalSwingLeftMid)
+
+	assert.NoError(t, err)
+	parameters := recorder.commands[0].Parameters
+	assert.Equal(t, int64(types.AutoSwingVertical), *parameters.FanAutoMode)
+	assert.Nil(t, parameters.AirSwingUD)
+	assert.Equal(t, int64(types.HorizontalSwingLeftMid), *parameters.AirSwingLR)
+}
+
+func TestCommandBuilder_AirSwingOneDirection(t *testing.T) {
+	recorder := &commandRecorder{}
+	server := newTestServer(t, map[string]http.HandlerFunc{
+		types.UrlPathControl: recorder.ServeHTTP,
+		types.UrlPathDeviceStatus: func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"parameters":{"fanAutoMode":3,"airSwingUD":2,"airSwingLR":2}}`))
+		},
+	})
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+
+	command := client.Device("living").Control().VerticalSwing(types.VerticalSwingDown)
+	_, err := command.Send(context.Background())
+
+	// the horizontal louvres keep swinging
+	assert.NoError(t, err)
+	parameters := recorder.commands[0].Parameters
+	assert.Equal(t, int64(types.AutoSwingHorizontal), *parameters.FanAutoMode)
+	assert.Equal(t, int64(types.VerticalSwingDown), *parameters.AirSwingUD)
+	assert.Nil(t, parameters.AirSwingLR)
+	assert.Equal(t, int64(types.AutoSwingHorizontal), *command.Parameters().FanAutoMode)
+}
+
+func TestDeviceParameters_Swing(t *testing.T) {
+	parameters := types.DeviceParameters{FanAutoMode: int64(types.AutoSwingHorizontal), AirSwingUD: 3, AirSwingLR: 2}
+
+	assert.Equal(t, types.VerticalSwingUpMid, parameters.VerticalSwing())
+	assert.Equal(t, types.HorizontalSwingAuto, parameters.HorizontalSwing())
+	assert.Equal(t, "up-mid", parameters.VerticalSwing().String())
+
+	swing, err := types.ParseHorizontalSwing("right-mid")
+	assert.NoError(t, err)
+	assert.Equal(t, types.HorizontalSwingRightMid, swing)
+	_, err = types.ParseVerticalSwing("sideways")
+	assert.Error(t, err)
+}
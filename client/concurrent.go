@@ -0,0 +1,87 @@
+package cloudcontrol
+
+import (
+	"context"
+	"github.com/jesper-nord/go-pcc/types"
+	"sync"
+)
+
+// DefaultConcurrency is the number of devices handled in parallel by the multi-device helpers.
+const DefaultConcurrency = 4
+
+// WithConcurrency sets the number of devices handled in parallel by StatusAll and ForEachDevice.
+func WithConcurrency(workers int) Option {
+	return func(c *Client) {
+		c.concurrency = workers
+	}
+}
+
+// StatusResult is the status of one device fetched by StatusAll.
+type StatusResult struct {
+	GUID   string
+	Status types.Device
+	Err    error
+}
+
+// ForEachDevice calls fn with a handle to each of the given devices, running at most
+// the configured number of calls in parallel. The returned errors are in the order of guids.
+func (c *Client) ForEachDevice(ctx context.Context, guids []string, fn func(ctx context.Context, device *Device) error) []error {
+	return c.parallel(ctx, len(guids), func(ctx context.Context, i int) error {
+		return fn(ctx, c.Device(guids[i]))
+	})
+}
+
+// StatusAll fetches the status of every device of the account in parallel.
+// Failures for single devices are reported in their StatusResult; the error is only
+// set when the device list itself cannot be fetched.
+func (c *Client) StatusAll(ctx context.Context) ([]StatusResult, error) {
+	guids, err := c.ListDevicesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]StatusResult, len(guids))
+	errs := c.parallel(ctx, len(guids), func(ctx context.Context, i int) error {
+		status, err := c.Device(guids[i]).Status(ctx)
+		results[i].Status = status
+		return err
+	})
+	for i := range results {
+		results[i].GUID = guids[i]
+		results[i].Err = errs[i]
+	}
+
+	return results, nil
+}
+
+// parallel calls fn for the indexes 0 to n-1 using a bounded pool of workers.
+func (c *Client) parallel(ctx context.Context, n int, fn func(ctx context.Context, i int) error) []error {
+	workers := c.concurrency
+	if workers <= 0 {
+		workers = DefaultConcurrency
+	}
+
+	errs := make([]error, n)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				errs[i] = fn(ctx, i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return errs
+}
@@ -0,0 +1,123 @@
+package cloudcontrol_test
+
+import (
+	"context"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+var multiGroupsBody = `{"groupCount":2,"groupList":[` +
+	`{"groupId":1,"groupName":"House","deviceList":[{"deviceGuid":"living","deviceName":"Living room"},{"deviceGuid":"bedroom","deviceName":"Bedroom"}]},` +
+	`{"groupId":2,"groupName":"Cabin","deviceList":[{"deviceGuid":"cabin","deviceName":"Cabin"},{"deviceGuid":"broken","deviceName":"Broken"}]}]}`
+
+// multiDeviceHandlers serve several devices, requiring the token handed out by sessionMock.
+func multiDeviceHandlers(logins *int32) map[string]http.HandlerFunc {
+	authorized := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-User-Authorization") != "token12345" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		}
+	}
+
+	return map[string]http.HandlerFunc{
+		types.UrlPathLogin: func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(logins, 1)
+			sessionMock(w, r)
+		},
+		types.UrlPathGroups: authorized(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(multiGroupsBody))
+		}),
+		types.UrlPathDeviceStatus: authorized(func(w http.ResponseWriter, r *http.Request) {
+			guid := strings.TrimPrefix(r.URL.Path, types.UrlPathDeviceStatus)
+			if guid == "broken" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_, _ = w.Write([]byte(`{"deviceGuid":"` + guid + `","parameters":{"operate":1}}`))
+		}),
+		types.UrlPathControl: authorized((&commandRecorder{}).ServeHTTP),
+	}
+}
+
+func TestStatusAll(t *testing.T) {
+	var logins int32
+	server := newTestServer(t, multiDeviceHandlers(&logins))
+	client := cloudcontrol.NewClientWithUrl(server.URL, cloudcontrol.WithConcurrency(2))
+	client.SetToken("token12345")
+
+	results, err := client.StatusAll(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(results))
+	for i, guid := range []string{"living", "bedroom", "cabin"} {
+		assert.Equal(t, guid, results[i].GUID)
+		assert.Equal(t, guid, results[i].Status.DeviceGUID)
+		assert.NoError(t, results[i].Err)
+	}
+	assert.Equal(t, "broken", results[3].GUID)
+	assert.Error(t, results[3].Err)
+}
+
+func TestClient_ConcurrentUseSharesOneLogin(t *testing.T) {
+	var logins int32
+	server := newTestServer(t, multiDeviceHandlers(&logins))
+	client := cloudcontrol.NewClientWithUrl(server.URL,
+		cloudcontrol.WithCredentials(cloudcontrol.StaticCredentials{Username: "user", Password: "pass"}),
+	)
+	client.SetToken("expired")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 60)
+	for i := 0; i < 20; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			_, err := client.Device("living").TurnOn(context.Background())
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := client.StatusAll(context.Background())
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			client.SetDevice("bedroom")
+			_, err := client.GetDeviceStatus()
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&logins))
+	assert.Equal(t, "token12345", client.Token())
+}
+
+func TestForEachDevice_StopsOnCancelledContext(t *testing.T) {
+	client := cloudcontrol.NewClientWithUrl("http://pcc.invalid")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	errs := client.ForEachDevice(ctx, []string{"a", "b"}, func(ctx context.Context, device *cloudcontrol.Device) error {
+		called = true
+		return nil
+	})
+
+	assert.False(t, called)
+	assert.ErrorIs(t, errs[0], context.Canceled)
+	assert.ErrorIs(t, errs[1], context.Canceled)
+}
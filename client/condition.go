@@ -0,0 +1,160 @@
+package cloudcontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jesper-nord/go-pcc/types"
+	"strconv"
+	"strings"
+)
+
+// ErrConflict is matched by every ConflictError.
+var ErrConflict = errors.New("conflict")
+
+// ConflictError is returned when a precondition of a conditional command no longer holds.
+type ConflictError struct {
+	// Field is the API name of the parameter that changed.
+	Field string
+	// Expected is the value required by the condition.
+	Expected any
+	// Actual is the current value of the parameter.
+	Actual any
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflict: %s is %v, expected %v", e.Field, e.Actual, e.Expected)
+}
+
+// Is makes a ConflictError match ErrConflict.
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// Condition is a precondition on the current device parameters.
+type Condition struct {
+	field    string
+	expected any
+	actual   func(types.DeviceParameters) any
+}
+
+// String describes the condition using the API name of the parameter.
+func (c Condition) String() string {
+	return fmt.Sprintf("%s=%v", c.field, c.expected)
+}
+
+// check returns a *ConflictError if the condition does not hold for the parameters.
+func (c Condition) check(parameters types.DeviceParameters) error {
+	if actual := c.actual(parameters); actual != c.expected {
+		return &ConflictError{Field: c.field, Expected: c.expected, Actual: actual}
+	}
+	return nil
+}
+
+// IfTemperature requires the set temperature to be the given value.
+func IfTemperature(temperature float64) Condition {
+	return Condition{field: "temperatureSet", expected: temperature, actual: func(p types.DeviceParameters) any {
+		return p.TemperatureSet
+	}}
+}
+
+// IfMode requires the device to be in the given mode.
+func IfMode(mode types.OperationMode) Condition {
+	return Condition{field: "operationMode", expected: mode, actual: func(p types.DeviceParameters) any {
+		return p.Mode()
+	}}
+}
+
+// IfPower requires the device to be switched on or off.
+func IfPower(state types.PowerState) Condition {
+	return Condition{field: "operate", expected: state, actual: func(p types.DeviceParameters) any {
+		return p.Power()
+	}}
+}
+
+// IfFanSpeed requires the fan to run at the given speed.
+func IfFanSpeed(speed types.FanSpeed) Condition {
+	return Condition{field: "fanSpeed", expected: speed, actual: func(p types.DeviceParameters) any {
+		return p.Fan()
+	}}
+}
+
+// IfEcoMode requires the device to be in the given eco mode.
+func IfEcoMode(mode types.EcoMode) Condition {
+	return Condition{field: "ecoMode", expected: mode, actual: func(p types.DeviceParameters) any {
+		return p.Eco()
+	}}
+}
+
+// ParseConditions parses a comma separated list of conditions such as "temp=19,mode=heat".
+// Supported keys are temp, mode, power, speed and ecomode.
+func ParseConditions(expression string) ([]Condition, error) {
+	var conditions []Condition
+	for _, term := range strings.Split(expression, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		key, value, found := strings.Cut(term, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid condition %q: expected key=value", term)
+		}
+		condition, err := parseCondition(strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition %q: %w", term, err)
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+func parseCondition(key string, value string) (Condition, error) {
+	switch key {
+	case "temp", "temperature":
+		temperature, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return Condition{}, err
+		}
+		return IfTemperature(temperature), nil
+	case "mode":
+		mode, err := types.ParseOperationMode(value)
+		return IfMode(mode), err
+	case "power":
+		state, err := types.ParsePowerState(value)
+		return IfPower(state), err
+	case "speed":
+		speed, err := types.ParseFanSpeed(value)
+		return IfFanSpeed(speed), err
+	case "ecomode":
+		mode, err := types.ParseEcoMode(value)
+		return IfEcoMode(mode), err
+	}
+	return Condition{}, fmt.Errorf("unknown key %q, expected one of temp, mode, power, speed, ecomode", key)
+}
+
+// If makes the command conditional: it is only sent if all conditions hold for the
+// current device status. Otherwise Send returns a *ConflictError.
+//
+// The status is read right before sending, which narrows but does not close the window
+// in which another client can change the device.
+func (b *CommandBuilder) If(conditions ...Condition) *CommandBuilder {
+	b.conditions = append(b.conditions, conditions...)
+	return b
+}
+
+// checkConditions reads the device status and checks the conditions against it.
+func (d *Device) checkConditions(ctx context.Context, conditions []Condition) error {
+	if len(conditions) == 0 {
+		return nil
+	}
+	status, err := d.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, condition := range conditions {
+		if err := condition.check(status.Parameters); err != nil {
+			return err
+		}
+	}
+	return nil
+}
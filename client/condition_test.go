@@ -0,0 +1,66 @@
+package cloudcontrol_test
+
+import (
+	"context"
+	"errors"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// conditionHandlers report a device heating to 19 degrees and record control commands.
+func conditionHandlers(recorder *commandRecorder) map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		types.UrlPathControl: recorder.ServeHTTP,
+		types.UrlPathDeviceStatus: func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"parameters":{"operate":1,"operationMode":3,"temperatureSet":19}}`))
+		},
+	}
+}
+
+func TestCommandBuilder_IfConditionsHold(t *testing.T) {
+	recorder := &commandRecorder{}
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, conditionHandlers(recorder)).URL)
+
+	_, err := client.Device("living").Control().Temperature(21).
+		If(cloudcontrol.IfTemperature(19), cloudcontrol.IfMode(types.ModeHeat), cloudcontrol.IfPower(types.PowerOn)).
+		Send(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"living"}, recorder.guids())
+}
+
+func TestCommandBuilder_IfConflict(t *testing.T) {
+	recorder := &commandRecorder{}
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, conditionHandlers(recorder)).URL)
+
+	_, err := client.Device("living").Control().Temperature(21).
+		If(cloudcontrol.IfTemperature(20)).
+		Send(context.Background())
+
+	var conflict *cloudcontrol.ConflictError
+	assert.ErrorIs(t, err, cloudcontrol.ErrConflict)
+	assert.True(t, errors.As(err, &conflict))
+	assert.Equal(t, "temperatureSet", conflict.Field)
+	assert.Equal(t, 19.0, conflict.Actual)
+	assert.Empty(t, recorder.guids())
+}
+
+func TestParseConditions(t *testing.T) {
+	conditions, err := cloudcontrol.ParseConditions("temp=19.5, mode=heat,power=on")
+
+	assert.NoError(t, err)
+	assert.Len(t, conditions, 3)
+	assert.Equal(t, "temperatureSet=19.5", conditions[0].String())
+	assert.Equal(t, "operationMode=heat", conditions[1].String())
+	assert.Equal(t, "operate=on", conditions[2].String())
+
+	_, err = cloudcontrol.ParseConditions("mode=hat")
+	assert.Error(t, err)
+	_, err = cloudcontrol.ParseConditions("humidity=40")
+	assert.Error(t, err)
+	_, err = cloudcontrol.ParseConditions("temp")
+	assert.Error(t, err)
+}
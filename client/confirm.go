@@ -0,0 +1,117 @@
+package cloudcontrol
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/labstack/gommon/log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotConfirmed is returned when a command is not reflected in the device status in time.
+var ErrNotConfirmed = errors.New("command not confirmed")
+
+// DefaultConfirmInterval is the delay between two status polls while confirming a command.
+const DefaultConfirmInterval = 2 * time.Second
+
+// ConfirmOptions controls how SendAndConfirm waits for a command to be applied.
+type ConfirmOptions struct {
+	// Timeout is how long to wait for the device to apply the command.
+	Timeout time.Duration
+	// Interval is the delay between two status polls, DefaultConfirmInterval if zero.
+	Interval time.Duration
+}
+
+// Confirmation reports which parameters of a command were applied by the device.
+type Confirmation struct {
+	// Applied lists the API names of the parameters found in the device status.
+	Applied []string
+	// Pending lists the API names of the parameters not (yet) found in the device status.
+	Pending []string
+	// Status is the last status read from the device.
+	Status types.Device
+}
+
+// Confirmed reports whether all parameters were applied.
+func (c Confirmation) Confirmed() bool {
+	return len(c.Pending) == 0
+}
+
+// SendAndConfirm sends the command and polls the device status until all parameters
+// are reflected or the timeout elapses. The cloud accepts commands for units it cannot
+// reach, so this is the only way to know that a change arrived at the unit.
+//
+// It returns an error wrapping ErrNotConfirmed on timeout and ErrDeviceOffline if the
+// status shows that the cloud lost contact with the unit.
+func (b *CommandBuilder) SendAndConfirm(ctx context.Context, opts ConfirmOptions) (Confirmation, error) {
+	if _, err := b.Send(ctx); err != nil {
+		return Confirmation{}, err
+	}
+
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultConfirmInterval
+	}
+	deadline := time.Now().Add(opts.Timeout)
+	parameters := b.Parameters()
+
+	for {
+		confirmation := Confirmation{}
+		status, err := b.device.Status(ctx)
+		if err != nil {
+			return confirmation, err
+		}
+		confirmation.Status = status
+		confirmation.Applied, confirmation.Pending = diffParameters(parameters, status.Parameters)
+
+		if confirmation.Confirmed() {
+			return confirmation, nil
+		}
+		if !status.Parameters.Reachable() {
+			return confirmation, fmt.Errorf("%w: %s not applied", ErrDeviceOffline, strings.Join(confirmation.Pending, ", "))
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return confirmation, fmt.Errorf("%w: %s still pending after %s", ErrNotConfirmed, strings.Join(confirmation.Pending, ", "), opts.Timeout)
+		}
+
+		log.Debugf("waiting for %s to be applied", strings.Join(confirmation.Pending, ", "))
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return confirmation, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// diffParameters compares requested control parameters with the current device parameters,
+// returning the API names of the parameters that match and of those that do not.
+func diffParameters(requested types.DeviceControlParameters, actual types.DeviceParameters) (applied []string, pending []string) {
+	want := parameterMap(requested)
+	have := parameterMap(actual)
+
+	for name, value := range want {
+		if have[name] == value {
+			applied = append(applied, name)
+		} else {
+			pending = append(pending, name)
+		}
+	}
+	sort.Strings(applied)
+	sort.Strings(pending)
+
+	return applied, pending
+}
+
+// parameterMap flattens parameters into their JSON representation, keyed by API name.
+func parameterMap(parameters any) map[string]any {
+	data, _ := json.Marshal(parameters)
+	values := map[string]any{}
+	_ = json.Unmarshal(data, &values)
+	return values
+}
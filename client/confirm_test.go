@@ -0,0 +1,80 @@
+package cloudcontrol_test
+
+import (
+	"context"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// confirmHandlers accept commands and report statusAfter once polls status requests have been made.
+func confirmHandlers(statusBefore string, statusAfter string, polls int32) map[string]http.HandlerFunc {
+	var calls int32
+	return map[string]http.HandlerFunc{
+		types.UrlPathControl: controlMock,
+		types.UrlPathDeviceStatus: func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < polls {
+				_, _ = w.Write([]byte(statusBefore))
+				return
+			}
+			_, _ = w.Write([]byte(statusAfter))
+		},
+	}
+}
+
+func TestSendAndConfirm_Applied(t *testing.T) {
+	server := newTestServer(t, confirmHandlers(
+		`{"parameters":{"operate":0,"temperatureSet":19}}`,
+		`{"parameters":{"operate":1,"temperatureSet":21.5}}`, 3))
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+
+	confirmation, err := client.Device("living").Control().On().Temperature(21.5).
+		SendAndConfirm(context.Background(), cloudcontrol.ConfirmOptions{Timeout: time.Second, Interval: time.Millisecond})
+
+	assert.NoError(t, err)
+	assert.True(t, confirmation.Confirmed())
+	assert.Equal(t, []string{"operate", "temperatureSet"}, confirmation.Applied)
+	assert.Equal(t, 21.5, confirmation.Status.Parameters.TemperatureSet)
+}
+
+func TestSendAndConfirm_Timeout(t *testing.T) {
+	server := newTestServer(t, confirmHandlers(
+		`{"parameters":{"operate":1,"temperatureSet":19}}`,
+		`{"parameters":{"operate":1,"temperatureSet":19}}`, 0))
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+
+	confirmation, err := client.Device("living").Control().On().Temperature(21.5).
+		SendAndConfirm(context.Background(), cloudcontrol.ConfirmOptions{Timeout: 20 * time.Millisecond, Interval: 5 * time.Millisecond})
+
+	assert.ErrorIs(t, err, cloudcontrol.ErrNotConfirmed)
+	assert.Equal(t, []string{"operate"}, confirmation.Applied)
+	assert.Equal(t, []string{"temperatureSet"}, confirmation.Pending)
+}
+
+func TestSendAndConfirm_Offline(t *testing.T) {
+	server := newTestServer(t, confirmHandlers(
+		`{"parameters":{"operate":0,"devRacCommunicateStatus":1}}`,
+		`{"parameters":{"operate":0,"devRacCommunicateStatus":1}}`, 0))
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+
+	_, err := client.Device("living").Control().On().
+		SendAndConfirm(context.Background(), cloudcontrol.ConfirmOptions{Timeout: time.Second, Interval: time.Millisecond})
+
+	assert.ErrorIs(t, err, cloudcontrol.ErrDeviceOffline)
+}
+
+func TestSendAndConfirm_NotOnline(t *testing.T) {
+	server := newTestServer(t, confirmHandlers(
+		`{"parameters":{"operate":0,"online":false}}`,
+		`{"parameters":{"operate":0,"online":false}}`, 0))
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+
+	_, err := client.Device("living").Control().On().
+		SendAndConfirm(context.Background(), cloudcontrol.ConfirmOptions{Timeout: time.Second, Interval: time.Millisecond})
+
+	assert.ErrorIs(t, err, cloudcontrol.ErrDeviceOffline)
+}
@@ -0,0 +1,123 @@
+package cloudcontrol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jesper-nord/go-pcc/types"
+	"net/url"
+	"time"
+)
+
+// Device is a handle to a single device of the account. It carries its own GUID,
+// so one authenticated Client can drive several devices.
+type Device struct {
+	client *Client
+	guid   string
+}
+
+// Device returns a handle to the device with the given GUID.
+func (c *Client) Device(guid string) *Device {
+	return &Device{client: c, guid: guid}
+}
+
+// GUID returns the GUID of the device.
+func (d *Device) GUID() string {
+	return d.guid
+}
+
+// Status gets all details for the device.
+func (d *Device) Status(ctx context.Context) (types.Device, error) {
+	body, err := d.client.doGetRequest(ctx, types.UrlPathDeviceStatus+url.QueryEscape(d.guid))
+	if err != nil {
+		return types.Device{}, err
+	}
+
+	device := types.Device{}
+	if err := decode(body, &device); err != nil {
+		return types.Device{}, err
+	}
+
+	return device, nil
+}
+
+// History fetches historical data of the device.
+func (d *Device) History(ctx context.Context, period types.HistoryPeriod) (types.History, error) {
+	postBody, _ := json.Marshal(map[string]string{
+		"dataMode":   fmt.Sprint(int64(period)),
+		"date":       time.Now().Format("20060102"),
+		"deviceGuid": d.guid,
+		"osTimezone": "+01:00",
+	})
+
+	body, err := d.client.doPostRequest(ctx, types.UrlPathHistory, postBody)
+	if err != nil {
+		return types.History{}, err
+	}
+
+	history := types.History{}
+	if err := decode(body, &history); err != nil {
+		return types.History{}, err
+	}
+
+	return history, nil
+}
+
+// SetTemperature sets the temperature of the device.
+func (d *Device) SetTemperature(ctx context.Context, temperature float64) ([]byte, error) {
+	return d.Control().Temperature(temperature).Send(ctx)
+}
+
+// SetFanSpeed sets the fan speed of the device.
+func (d *Device) SetFanSpeed(ctx context.Context, fanSpeed types.FanSpeed) ([]byte, error) {
+	return d.Control().FanSpeed(fanSpeed).Send(ctx)
+}
+
+// TurnOn switches the device on.
+func (d *Device) TurnOn(ctx context.Context) ([]byte, error) {
+	return d.Control().On().Send(ctx)
+}
+
+// TurnOff switches the device off.
+func (d *Device) TurnOff(ctx context.Context) ([]byte, error) {
+	return d.Control().Off().Send(ctx)
+}
+
+// SetMode sets the device to the requested AC mode.
+func (d *Device) SetMode(ctx context.Context, mode types.OperationMode) ([]byte, error) {
+	return d.Control().Mode(mode).Send(ctx)
+}
+
+// SetEcoMode sets the device to the requested eco mode.
+func (d *Device) SetEcoMode(ctx context.Context, mode types.EcoMode) ([]byte, error) {
+	return d.Control().EcoMode(mode).Send(ctx)
+}
+
+// SetAirSwing sets the vertical and horizontal louvre positions of the device.
+// Pass VerticalSwingAuto or HorizontalSwingAuto to let the louvres swing.
+func (d *Device) SetAirSwing(ctx context.Context, vertical types.VerticalSwing, horizontal types.HorizontalSwing) ([]byte, error) {
+	return d.Control().VerticalSwing(vertical).HorizontalSwing(horizontal).Send(ctx)
+}
+
+// SetNanoe sets the nanoe mode of the device.
+func (d *Device) SetNanoe(ctx context.Context, mode types.NanoeMode) ([]byte, error) {
+	return d.Control().Nanoe(mode).Send(ctx)
+}
+
+// SetEcoNavi switches ecoNavi on or off.
+func (d *Device) SetEcoNavi(ctx context.Context, state types.FeatureState) ([]byte, error) {
+	return d.Control().EcoNavi(state).Send(ctx)
+}
+
+// SetIAuto switches iAuto-X on or off.
+func (d *Device) SetIAuto(ctx context.Context, state types.FeatureState) ([]byte, error) {
+	return d.Control().IAuto(state).Send(ctx)
+}
+
+// control sends the given parameters to the device.
+func (d *Device) control(ctx context.Context, parameters types.DeviceControlParameters) ([]byte, error) {
+	return d.client.control(ctx, types.Command{
+		DeviceGUID: d.guid,
+		Parameters: parameters,
+	})
+}
@@ -0,0 +1,76 @@
+package cloudcontrol_test
+
+import (
+	"context"
+	"encoding/json"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// commandRecorder is a control endpoint mock that records the commands it receives.
+type commandRecorder struct {
+	mu       sync.Mutex
+	commands []types.Command
+}
+
+func (r *commandRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	command := types.Command{}
+	_ = json.NewDecoder(req.Body).Decode(&command)
+	r.mu.Lock()
+	r.commands = append(r.commands, command)
+	r.mu.Unlock()
+	_, _ = w.Write([]byte(types.SuccessResponse))
+}
+
+func (r *commandRecorder) guids() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var guids []string
+	for _, command := range r.commands {
+		guids = append(guids, command.DeviceGUID)
+	}
+	return guids
+}
+
+func TestDevice_ControlsOwnGUID(t *testing.T) {
+	recorder := &commandRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+
+	living := client.Device("living")
+	bedroom := client.Device("bedroom")
+	_, err1 := living.TurnOn(context.Background())
+	_, err2 := bedroom.SetTemperature(context.Background(), 21.5)
+
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.Equal(t, []string{"living", "bedroom"}, recorder.guids())
+	assert.Equal(t, int64(1), *recorder.commands[0].Parameters.Operate)
+	assert.Equal(t, 21.5, *recorder.commands[1].Parameters.TemperatureSet)
+	assert.Equal(t, "", client.DeviceGUID)
+}
+
+func TestDevice_Status(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		_, _ = w.Write([]byte(`{"deviceGuid":"CZ-CAPWFC1+B8B7F1B3E326","parameters":{"operate":1,"temperatureSet":19.5}}`))
+	}))
+	defer server.Close()
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+
+	device := client.Device("CZ-CAPWFC1+B8B7F1B3E326")
+	status, err := device.Status(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 19.5, status.Parameters.TemperatureSet)
+	assert.True(t, strings.HasSuffix(path, "CZ-CAPWFC1%2BB8B7F1B3E326"))
+	assert.Equal(t, "CZ-CAPWFC1+B8B7F1B3E326", device.GUID())
+}
@@ -0,0 +1,94 @@
+package cloudcontrol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jesper-nord/go-pcc/types"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrUnauthorized is returned when the session token is missing, invalid or expired.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrDeviceOffline is returned when the cloud reports the device as unreachable.
+	ErrDeviceOffline = errors.New("device offline")
+	// ErrRateLimited is returned when Panasonic throttles the account.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnexpectedResponse is returned when a response body cannot be decoded.
+	ErrUnexpectedResponse = errors.New("unexpected response")
+)
+
+// APIError is returned when Panasonic Comfort Cloud rejects a request.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Code is the PCC result or error code found in the response body, if any.
+	Code int64
+	// Message is the error message found in the response body, if any.
+	Message string
+	// Body is the raw response body.
+	Body []byte
+	// RetryAfter is the delay requested by the Retry-After header, if any.
+	RetryAfter time.Duration
+	// Attempts is the number of times the request was sent.
+	Attempts int
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("pcc: HTTP %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Code != 0 {
+		msg += fmt.Sprintf(", code %d", e.Code)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	} else if len(e.Body) > 0 {
+		msg += ": " + string(e.Body)
+	}
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" (after %d attempts)", e.Attempts)
+	}
+	return msg
+}
+
+// Is makes errors.Is match an APIError against the sentinel errors of this package.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.Code == types.ResultCodeTokenExpired
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrDeviceOffline:
+		return e.Code == types.ResultCodeDeviceOffline
+	}
+	return false
+}
+
+// newAPIError builds an APIError from a response, extracting any PCC error code from the body.
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode, Body: body}
+
+	var payload struct {
+		Code    int64  `json:"code"`
+		Result  int64  `json:"result"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &payload) == nil {
+		apiErr.Code = payload.Code
+		if apiErr.Code == 0 {
+			apiErr.Code = payload.Result
+		}
+		apiErr.Message = payload.Message
+	}
+
+	return apiErr
+}
+
+// decode unmarshals a response body, wrapping failures in ErrUnexpectedResponse.
+func decode(body []byte, v any) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%w: %v: %s", ErrUnexpectedResponse, err, body)
+	}
+	return nil
+}
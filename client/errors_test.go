@@ -0,0 +1,72 @@
+package cloudcontrol_test
+
+import (
+	"errors"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// respond answers every request with status and body.
+func respond(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func TestAPIError_Unauthorized(t *testing.T) {
+	server := newTestServer(t, map[string]http.HandlerFunc{"/": respond(http.StatusUnauthorized, `{"message":"Token expires","code":4100}`)})
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+
+	_, err := client.GetGroups()
+
+	var apiErr *cloudcontrol.APIError
+	assert.ErrorIs(t, err, cloudcontrol.ErrUnauthorized)
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	assert.Equal(t, int64(types.ResultCodeTokenExpired), apiErr.Code)
+	assert.Equal(t, "Token expires", apiErr.Message)
+}
+
+func TestAPIError_RateLimited(t *testing.T) {
+	server := newTestServer(t, map[string]http.HandlerFunc{"/": respond(http.StatusTooManyRequests, ``)})
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+
+	_, err := client.GetDeviceStatus()
+
+	assert.ErrorIs(t, err, cloudcontrol.ErrRateLimited)
+	assert.NotErrorIs(t, err, cloudcontrol.ErrUnauthorized)
+}
+
+func TestAPIError_DeviceOffline(t *testing.T) {
+	server := newTestServer(t, map[string]http.HandlerFunc{"/": respond(http.StatusForbidden, `{"message":"Device offline","code":5005}`)})
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+
+	_, err := client.TurnOn()
+
+	assert.ErrorIs(t, err, cloudcontrol.ErrDeviceOffline)
+}
+
+func TestControl_UnsuccessfulResult(t *testing.T) {
+	server := newTestServer(t, map[string]http.HandlerFunc{"/": respond(http.StatusOK, `{"result":1}`)})
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+
+	body, err := client.TurnOff()
+
+	var apiErr *cloudcontrol.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, int64(1), apiErr.Code)
+	assert.Equal(t, `{"result":1}`, string(body))
+}
+
+func TestGetGroups_MalformedBody(t *testing.T) {
+	server := newTestServer(t, map[string]http.HandlerFunc{"/": respond(http.StatusOK, `<html>maintenance</html>`)})
+	client := cloudcontrol.NewClientWithUrl(server.URL)
+
+	_, err := client.GetGroups()
+
+	assert.ErrorIs(t, err, cloudcontrol.ErrUnexpectedResponse)
+}
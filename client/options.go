@@ -0,0 +1,86 @@
+package cloudcontrol
+
+import (
+	"github.com/labstack/gommon/log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultTimeout is the HTTP timeout used when no other timeout or client is configured.
+const DefaultTimeout = 30 * time.Second
+
+// Option configures a Client created by NewClient or NewClientWithUrl.
+type Option func(*Client)
+
+// WithHTTPClient makes the client send all requests through the given http.Client.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTransport sets the http.RoundTripper used to send requests.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		httpClient := *c.http()
+		httpClient.Transport = transport
+		c.httpClient = &httpClient
+	}
+}
+
+// WithTimeout sets the timeout for each HTTP request. Zero means no timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		httpClient := *c.http()
+		httpClient.Timeout = timeout
+		c.httpClient = &httpClient
+	}
+}
+
+// WithProxy routes all requests through the given proxy URL.
+// It only applies when the configured transport is an *http.Transport.
+func WithProxy(proxy *url.URL) Option {
+	return func(c *Client) {
+		transport, ok := c.transport().(*http.Transport)
+		if !ok {
+			log.Warnf("proxy %s ignored: transport %T is not an *http.Transport", proxy, c.http().Transport)
+			return
+		}
+		transport = transport.Clone()
+		transport.Proxy = http.ProxyURL(proxy)
+		WithTransport(transport)(c)
+	}
+}
+
+// WithUserAgent overrides the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithAppVersion overrides the X-APP-VERSION header sent with every request.
+func WithAppVersion(appVersion string) Option {
+	return func(c *Client) {
+		c.appVersion = appVersion
+	}
+}
+
+// defaultHTTPClient is shared by clients that were not created through NewClient,
+// so that zero value clients still reuse connections.
+var defaultHTTPClient = newHTTPClient()
+
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout:   DefaultTimeout,
+		Transport: http.DefaultTransport.(*http.Transport).Clone(),
+	}
+}
+
+func (c *Client) transport() http.RoundTripper {
+	if c.http().Transport == nil {
+		return http.DefaultTransport
+	}
+	return c.http().Transport
+}
@@ -0,0 +1,68 @@
+package cloudcontrol_test
+
+import (
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestWithTransport_UsedForRequests(t *testing.T) {
+	var requests []*http.Request
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests = append(requests, req)
+		rec := httptest.NewRecorder()
+		_, _ = rec.WriteString(groupsBody)
+		return rec.Result(), nil
+	})
+
+	client := cloudcontrol.NewClientWithUrl("http://pcc.invalid",
+		cloudcontrol.WithTransport(transport),
+		cloudcontrol.WithUserAgent("agent/1.0"),
+		cloudcontrol.WithAppVersion("9.9.9"),
+	)
+	_, err := client.GetGroups()
+	_, err2 := client.GetGroups()
+
+	assert.NoError(t, err)
+	assert.NoError(t, err2)
+	assert.Equal(t, 2, len(requests))
+	assert.Equal(t, "agent/1.0", requests[0].Header.Get("User-Agent"))
+	assert.Equal(t, "9.9.9", requests[0].Header.Get("X-APP-VERSION"))
+}
+
+func TestWithTimeout_AbortsSlowRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := cloudcontrol.NewClientWithUrl(server.URL, cloudcontrol.WithTimeout(10*time.Millisecond))
+	_, err := client.GetGroups()
+
+	assert.Error(t, err)
+}
+
+func TestZeroValueClient_UsesDefaultHeaders(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		_, _ = w.Write([]byte(groupsBody))
+	}))
+	defer server.Close()
+
+	client := cloudcontrol.Client{Server: server.URL}
+	_, err := client.GetGroups()
+
+	assert.NoError(t, err)
+	assert.Equal(t, types.DefaultUserAgent, userAgent)
+}
@@ -0,0 +1,283 @@
+package cloudcontrol
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jesper-nord/go-pcc/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// EndpointClass groups Comfort Cloud endpoints that share a request budget.
+type EndpointClass int
+
+const (
+	EndpointLogin EndpointClass = iota
+	EndpointStatus
+	EndpointHistory
+	EndpointControl
+)
+
+func (e EndpointClass) String() string {
+	switch e {
+	case EndpointLogin:
+		return "login"
+	case EndpointStatus:
+		return "status"
+	case EndpointHistory:
+		return "history"
+	case EndpointControl:
+		return "control"
+	}
+	return fmt.Sprintf("EndpointClass(%d)", int(e))
+}
+
+// endpointClassOf returns the endpoint class of a request path.
+func endpointClassOf(url string) EndpointClass {
+	switch url {
+	case types.UrlPathLogin, types.UrlPathValidate:
+		return EndpointLogin
+	case types.UrlPathHistory:
+		return EndpointHistory
+	case types.UrlPathControl:
+		return EndpointControl
+	}
+	return EndpointStatus
+}
+
+// RateLimit is a token bucket allowing Burst requests at once, refilled at Rate requests per second.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultRateLimits are conservative limits per endpoint class.
+// Panasonic does not publish its limits, these are meant to stay well below them.
+var DefaultRateLimits = map[EndpointClass]RateLimit{
+	EndpointLogin:   {Rate: 1.0 / 60, Burst: 3},
+	EndpointStatus:  {Rate: 1.0 / 5, Burst: 10},
+	EndpointHistory: {Rate: 1.0 / 30, Burst: 5},
+	EndpointControl: {Rate: 1.0 / 3, Burst: 5},
+}
+
+// RateLimitError is returned by a fail-fast RateLimiter when a request would exceed its limit.
+type RateLimitError struct {
+	Class      EndpointClass
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("client-side rate limit for %s requests exceeded, retry in %s", e.Class, e.RetryAfter.Round(time.Millisecond))
+}
+
+// Is makes a RateLimitError match ErrRateLimited.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RateLimiter limits the requests made through one or more clients.
+// It is safe for concurrent use.
+type RateLimiter struct {
+	mu        sync.Mutex
+	limits    map[EndpointClass]RateLimit
+	buckets   map[EndpointClass]*bucket
+	failFast  bool
+	statePath string
+}
+
+// RateLimiterOption configures a RateLimiter.
+type RateLimiterOption func(*RateLimiter)
+
+// FailFast makes the rate limiter return a *RateLimitError instead of waiting for a free slot.
+func FailFast() RateLimiterOption {
+	return func(l *RateLimiter) {
+		l.failFast = true
+	}
+}
+
+// SharedAcrossProcesses keeps the rate limiter state in the file at path,
+// so that all processes using the same file share one budget.
+func SharedAcrossProcesses(path string) RateLimiterOption {
+	return func(l *RateLimiter) {
+		l.statePath = path
+	}
+}
+
+// NewRateLimiter creates a rate limiter. Endpoint classes without a limit are not limited.
+func NewRateLimiter(limits map[EndpointClass]RateLimit, opts ...RateLimiterOption) *RateLimiter {
+	l := &RateLimiter{
+		limits:  limits,
+		buckets: map[EndpointClass]*bucket{},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// WithRateLimiter makes every request of the client wait for the given rate limiter.
+// The same rate limiter may be shared by several clients.
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(c *Client) {
+		c.rateLimiter = limiter
+	}
+}
+
+// Wait blocks until a request of the given class is allowed, or returns an error
+// if ctx is done first. In fail-fast mode it never blocks.
+func (l *RateLimiter) Wait(ctx context.Context, class EndpointClass) error {
+	if l == nil {
+		return nil
+	}
+	limit, ok := l.limits[class]
+	if !ok || limit.Rate <= 0 {
+		return nil
+	}
+
+	delay, err := l.reserve(ctx, class, limit)
+	if err != nil || delay <= 0 {
+		return err
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		_ = l.release(class, limit)
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// bucket is the state of a token bucket.
+type bucket struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// take refills the bucket and takes a token, returning how long the caller must wait for it.
+// In fail-fast mode a token is only taken if one is available right away.
+func (b *bucket) take(limit RateLimit, now time.Time, failFast bool) time.Duration {
+	if b.Updated.IsZero() {
+		b.Tokens = float64(limit.Burst)
+	} else if elapsed := now.Sub(b.Updated); elapsed > 0 {
+		b.Tokens += elapsed.Seconds() * limit.Rate
+	}
+	if b.Tokens > float64(limit.Burst) {
+		b.Tokens = float64(limit.Burst)
+	}
+	b.Updated = now
+
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return 0
+	}
+	delay := time.Duration((1 - b.Tokens) / limit.Rate * float64(time.Second))
+	if !failFast {
+		b.Tokens--
+	}
+	return delay
+}
+
+func (l *RateLimiter) reserve(ctx context.Context, class EndpointClass, limit RateLimit) (time.Duration, error) {
+	var delay time.Duration
+	err := l.update(ctx, func(buckets map[EndpointClass]*bucket) {
+		b, ok := buckets[class]
+		if !ok {
+			b = &bucket{}
+			buckets[class] = b
+		}
+		delay = b.take(limit, time.Now(), l.failFast)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if delay > 0 && l.failFast {
+		return 0, &RateLimitError{Class: class, RetryAfter: delay}
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		_ = l.release(class, limit)
+		return 0, &RateLimitError{Class: class, RetryAfter: delay}
+	}
+	return delay, nil
+}
+
+// release gives back a token that was reserved but not used.
+func (l *RateLimiter) release(class EndpointClass, limit RateLimit) error {
+	return l.update(context.Background(), func(buckets map[EndpointClass]*bucket) {
+		if b, ok := buckets[class]; ok && b.Tokens < float64(limit.Burst) {
+			b.Tokens++
+		}
+	})
+}
+
+// update applies fn to the bucket state, reading and writing the shared state file if configured.
+func (l *RateLimiter) update(ctx context.Context, fn func(map[EndpointClass]*bucket)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.statePath == "" {
+		fn(l.buckets)
+		return nil
+	}
+
+	unlock, err := lockFile(ctx, l.statePath+".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	buckets := map[EndpointClass]*bucket{}
+	data, err := os.ReadFile(l.statePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if len(data) > 0 {
+		// a corrupt state file is replaced with fresh buckets
+		_ = json.Unmarshal(data, &buckets)
+	}
+
+	fn(buckets)
+
+	data, err = json.Marshal(buckets)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(l.statePath, data, 0600)
+}
+
+// staleLockAge is the age after which a lock file is assumed to be left over by a crashed process.
+const staleLockAge = 10 * time.Second
+
+// lockFile acquires an exclusive lock by creating the file at path, waiting while another process holds it.
+func lockFile(ctx context.Context, path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(path)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package cloudcontrol_test
+
+import (
+	"context"
+	"errors"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_FailFast(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, map[string]http.HandlerFunc{"/": flaky(0, 0, groupsBody, &calls)})
+	limiter := cloudcontrol.NewRateLimiter(map[cloudcontrol.EndpointClass]cloudcontrol.RateLimit{
+		cloudcontrol.EndpointStatus: {Rate: 1.0 / 60, Burst: 2},
+	}, cloudcontrol.FailFast())
+	client := cloudcontrol.NewClientWithUrl(server.URL, cloudcontrol.WithRateLimiter(limiter))
+
+	_, err1 := client.GetGroups()
+	_, err2 := client.GetGroups()
+	_, err3 := client.GetGroups()
+
+	var limitErr *cloudcontrol.RateLimitError
+	assert.NoError(t, err1)
+	assert.NoError(t, err2)
+	assert.ErrorIs(t, err3, cloudcontrol.ErrRateLimited)
+	assert.True(t, errors.As(err3, &limitErr))
+	assert.Equal(t, cloudcontrol.EndpointStatus, limitErr.Class)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRateLimiter_BlocksUntilTokenAvailable(t *testing.T) {
+	limiter := cloudcontrol.NewRateLimiter(map[cloudcontrol.EndpointClass]cloudcontrol.RateLimit{
+		cloudcontrol.EndpointControl: {Rate: 20, Burst: 1},
+	})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, limiter.Wait(context.Background(), cloudcontrol.EndpointControl))
+	}
+
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	assert.NoError(t, limiter.Wait(context.Background(), cloudcontrol.EndpointHistory))
+}
+
+func TestRateLimiter_RespectsContextDeadline(t *testing.T) {
+	limiter := cloudcontrol.NewRateLimiter(map[cloudcontrol.EndpointClass]cloudcontrol.RateLimit{
+		cloudcontrol.EndpointLogin: {Rate: 1.0 / 60, Burst: 1},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	assert.NoError(t, limiter.Wait(ctx, cloudcontrol.EndpointLogin))
+	assert.ErrorIs(t, limiter.Wait(ctx, cloudcontrol.EndpointLogin), cloudcontrol.ErrRateLimited)
+}
+
+func TestRateLimiter_SharedAcrossProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit")
+	limits := map[cloudcontrol.EndpointClass]cloudcontrol.RateLimit{
+		cloudcontrol.EndpointStatus: {Rate: 1.0 / 60, Burst: 1},
+	}
+	first := cloudcontrol.NewRateLimiter(limits, cloudcontrol.SharedAcrossProcesses(path), cloudcontrol.FailFast())
+	second := cloudcontrol.NewRateLimiter(limits, cloudcontrol.SharedAcrossProcesses(path), cloudcontrol.FailFast())
+
+	assert.NoError(t, first.Wait(context.Background(), cloudcontrol.EndpointStatus))
+	assert.ErrorIs(t, second.Wait(context.Background(), cloudcontrol.EndpointStatus), cloudcontrol.ErrRateLimited)
+}
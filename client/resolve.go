@@ -0,0 +1,109 @@
+package cloudcontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jesper-nord/go-pcc/types"
+	"strings"
+)
+
+// GroupSelectorPrefix selects every device of a group, e.g. "group:My House".
+const GroupSelectorPrefix = "group:"
+
+// ErrAmbiguousDevice is matched by every AmbiguousDeviceError.
+var ErrAmbiguousDevice = errors.New("ambiguous device")
+
+// AmbiguousDeviceError is returned when a selector meant for a single device matches several.
+type AmbiguousDeviceError struct {
+	// Selector is the device name or GUID prefix that was given.
+	Selector string
+	// Matches are the devices matching the selector.
+	Matches []types.Device
+}
+
+func (e *AmbiguousDeviceError) Error() string {
+	var matches []string
+	for _, device := range e.Matches {
+		matches = append(matches, fmt.Sprintf("%s (%s)", device.DeviceName, device.DeviceGUID))
+	}
+	return fmt.Sprintf("ambiguous device %q matches %s", e.Selector, strings.Join(matches, ", "))
+}
+
+// Is makes an AmbiguousDeviceError match ErrAmbiguousDevice.
+func (e *AmbiguousDeviceError) Is(target error) bool {
+	return target == ErrAmbiguousDevice
+}
+
+// MatchDevices returns the devices of the account selected by selector, which is one of:
+//   - "group:<GroupName>", selecting every device of the group
+//   - a device GUID
+//   - a device name as shown in the app
+//   - a prefix of a device GUID
+//
+// Names and prefixes are compared case-insensitively and must match a single device,
+// otherwise an *AmbiguousDeviceError is returned. An error wrapping ErrDeviceNotFound
+// is returned if nothing matches.
+func MatchDevices(groups types.Groups, selector string) ([]types.Device, error) {
+	if name, ok := strings.CutPrefix(selector, GroupSelectorPrefix); ok {
+		for _, group := range groups.Groups {
+			if strings.EqualFold(group.GroupName, name) && len(group.Devices) > 0 {
+				return group.Devices, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: no devices in group %q", ErrDeviceNotFound, name)
+	}
+
+	var devices []types.Device
+	for _, group := range groups.Groups {
+		devices = append(devices, group.Devices...)
+	}
+
+	for _, device := range devices {
+		if device.DeviceGUID == selector {
+			return []types.Device{device}, nil
+		}
+	}
+
+	matchers := []func(types.Device) bool{
+		func(d types.Device) bool { return strings.EqualFold(d.DeviceName, selector) },
+		func(d types.Device) bool {
+			return strings.HasPrefix(strings.ToUpper(d.DeviceGUID), strings.ToUpper(selector))
+		},
+	}
+	for _, matches := range matchers {
+		var found []types.Device
+		for _, device := range devices {
+			if selector != "" && matches(device) {
+				found = append(found, device)
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found, nil
+		}
+		return nil, &AmbiguousDeviceError{Selector: selector, Matches: found}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrDeviceNotFound, selector)
+}
+
+// ResolveDevices returns handles to the devices selected by selector, see MatchDevices.
+func (c *Client) ResolveDevices(ctx context.Context, selector string) ([]*Device, error) {
+	groups, err := c.GetGroupsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := MatchDevices(groups, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]*Device, 0, len(matches))
+	for _, match := range matches {
+		devices = append(devices, c.Device(match.DeviceGUID))
+	}
+	return devices, nil
+}
@@ -0,0 +1,66 @@
+package cloudcontrol_test
+
+import (
+	"context"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var resolveGroups = types.Groups{Groups: []types.Group{
+	{GroupName: "Upstairs", Devices: []types.Device{
+		{DeviceGUID: "CZ-CAPWFC1+B8B7F1B3E326", DeviceName: "Bedroom"},
+		{DeviceGUID: "CZ-CAPWFC1+B8B7F1B3E327", DeviceName: "Office"},
+	}},
+	{GroupName: "Downstairs", Devices: []types.Device{
+		{DeviceGUID: "CZ-CAPWFC1+A1A1A1A1A1A1", DeviceName: "Living room"},
+		{DeviceGUID: "CZ-CAPWFC1+A2A2A2A2A2A2", DeviceName: "office"},
+	}},
+}}
+
+func matchedGUIDs(t *testing.T, selector string) []string {
+	devices, err := cloudcontrol.MatchDevices(resolveGroups, selector)
+	assert.NoError(t, err)
+	var guids []string
+	for _, device := range devices {
+		guids = append(guids, device.DeviceGUID)
+	}
+	return guids
+}
+
+func TestMatchDevices(t *testing.T) {
+	assert.Equal(t, []string{"CZ-CAPWFC1+B8B7F1B3E326"}, matchedGUIDs(t, "CZ-CAPWFC1+B8B7F1B3E326"))
+	assert.Equal(t, []string{"CZ-CAPWFC1+A1A1A1A1A1A1"}, matchedGUIDs(t, "living ROOM"))
+	assert.Equal(t, []string{"CZ-CAPWFC1+A2A2A2A2A2A2"}, matchedGUIDs(t, "cz-capwfc1+a2"))
+	assert.Equal(t, []string{"CZ-CAPWFC1+B8B7F1B3E326", "CZ-CAPWFC1+B8B7F1B3E327"}, matchedGUIDs(t, "group:upstairs"))
+}
+
+func TestMatchDevices_Ambiguous(t *testing.T) {
+	_, nameErr := cloudcontrol.MatchDevices(resolveGroups, "Office")
+	_, prefixErr := cloudcontrol.MatchDevices(resolveGroups, "CZ-CAPWFC1+B8")
+
+	assert.ErrorIs(t, nameErr, cloudcontrol.ErrAmbiguousDevice)
+	assert.ErrorIs(t, prefixErr, cloudcontrol.ErrAmbiguousDevice)
+	assert.EqualError(t, nameErr, `ambiguous device "Office" matches Office (CZ-CAPWFC1+B8B7F1B3E327), office (CZ-CAPWFC1+A2A2A2A2A2A2)`)
+}
+
+func TestMatchDevices_NotFound(t *testing.T) {
+	_, err1 := cloudcontrol.MatchDevices(resolveGroups, "Kitchen")
+	_, err2 := cloudcontrol.MatchDevices(resolveGroups, "group:Attic")
+	_, err3 := cloudcontrol.MatchDevices(resolveGroups, "")
+
+	assert.ErrorIs(t, err1, cloudcontrol.ErrDeviceNotFound)
+	assert.ErrorIs(t, err2, cloudcontrol.ErrDeviceNotFound)
+	assert.ErrorIs(t, err3, cloudcontrol.ErrDeviceNotFound)
+}
+
+func TestResolveDevices(t *testing.T) {
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, capabilityHandlers(&commandRecorder{})).URL)
+
+	devices, err := client.ResolveDevices(context.Background(), "alaior-home")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(devices))
+	assert.Equal(t, "CZ-CAPWFC1+B8B7F1B3E326", devices[0].GUID())
+}
@@ -0,0 +1,158 @@
+package cloudcontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/labstack/gommon/log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how requests failing with transient errors are retried.
+//
+// Reads are retried on network errors, HTTP 429 and HTTP 5xx. Control commands
+// are only retried when the request provably never reached Panasonic, that is
+// when the connection could not be established or the request was rate limited.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled for every further retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts, except for delays requested by a Retry-After header.
+	MaxDelay time.Duration
+	// Jitter randomises each delay by up to this fraction of it, between 0 and 1.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is a retry policy suitable for interactive use.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+	Jitter:      0.2,
+}
+
+// WithRetryPolicy makes the client retry transient failures according to policy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// doRequest sends a request, retrying transient failures according to the retry policy.
+func (c *Client) doRequest(ctx context.Context, method string, url string, postbody []byte) ([]byte, error) {
+	idempotent := url != types.UrlPathControl
+	class := endpointClassOf(url)
+
+	for attempt := 1; ; attempt++ {
+		if err := c.rateLimiter.Wait(ctx, class); err != nil {
+			return nil, withAttempts(err, attempt-1)
+		}
+
+		body, err := c.sendRequest(ctx, method, url, postbody)
+		if err == nil {
+			return body, nil
+		}
+
+		if attempt >= c.retryPolicy.MaxAttempts || ctx.Err() != nil || !retryable(err, idempotent) {
+			return body, withAttempts(err, attempt)
+		}
+
+		delay := c.retryPolicy.delay(attempt, err)
+		log.Debugf("%s %s failed (attempt %d/%d), retrying in %s: %v", method, url, attempt, c.retryPolicy.MaxAttempts, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return body, withAttempts(err, attempt)
+		case <-timer.C:
+		}
+	}
+}
+
+// delay returns how long to wait after the given failed attempt.
+func (p RetryPolicy) delay(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration(p.Jitter * (2*rand.Float64() - 1) * float64(delay))
+	}
+	if delay < 0 {
+		delay = 0
+	}
+
+	return delay
+}
+
+// retryable reports whether a failed request may be sent again.
+func retryable(err error, idempotent bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.StatusCode == http.StatusTooManyRequests {
+			return true
+		}
+		return idempotent && apiErr.StatusCode >= http.StatusInternalServerError
+	}
+	if errors.Is(err, ErrUnexpectedResponse) {
+		return false
+	}
+
+	return idempotent || neverSent(err)
+}
+
+// neverSent reports whether err shows that the request could not have reached the server.
+func neverSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// withAttempts records the number of attempts made in the returned error.
+func withAttempts(err error, attempts int) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		apiErr.Attempts = attempts
+		return err
+	}
+	if attempts > 1 {
+		return fmt.Errorf("%w (after %d attempts)", err, attempts)
+	}
+	return err
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
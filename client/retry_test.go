@@ -0,0 +1,109 @@
+package cloudcontrol_test
+
+import (
+	"errors"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = cloudcontrol.RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    5 * time.Millisecond,
+	Jitter:      0.5,
+}
+
+// flaky fails the first failures requests with status before answering with body.
+func flaky(failures int32, status int, body string, calls *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(calls, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+func TestRetry_ReadRecoversFromServerErrors(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, map[string]http.HandlerFunc{"/": flaky(2, http.StatusServiceUnavailable, groupsBody, &calls)})
+	client := cloudcontrol.NewClientWithUrl(server.URL, cloudcontrol.WithRetryPolicy(testRetryPolicy))
+
+	_, err := client.GetGroups()
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestRetry_ReadGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, map[string]http.HandlerFunc{"/": flaky(10, http.StatusBadGateway, groupsBody, &calls)})
+	client := cloudcontrol.NewClientWithUrl(server.URL, cloudcontrol.WithRetryPolicy(testRetryPolicy))
+
+	_, err := client.GetGroups()
+
+	var apiErr *cloudcontrol.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 3, apiErr.Attempts)
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestRetry_ControlNotRetriedOnServerError(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, map[string]http.HandlerFunc{"/": flaky(1, http.StatusInternalServerError, types.SuccessResponse, &calls)})
+	client := cloudcontrol.NewClientWithUrl(server.URL, cloudcontrol.WithRetryPolicy(testRetryPolicy))
+
+	_, err := client.TurnOn()
+
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestRetry_ControlRetriedWhenRateLimited(t *testing.T) {
+	var calls int32
+	server := newTestServer(t, map[string]http.HandlerFunc{"/": flaky(1, http.StatusTooManyRequests, types.SuccessResponse, &calls)})
+	client := cloudcontrol.NewClientWithUrl(server.URL, cloudcontrol.WithRetryPolicy(testRetryPolicy))
+
+	_, err := client.TurnOn()
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), calls)
+}
+
+func TestRetry_ControlRetriedWhenConnectionRefused(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	client := cloudcontrol.NewClientWithUrl(server.URL, cloudcontrol.WithRetryPolicy(testRetryPolicy))
+
+	_, err := client.TurnOn()
+
+	assert.ErrorContains(t, err, "after 3 attempts")
+}
+
+func TestRetry_HonorsRetryAfter(t *testing.T) {
+	var calls int32
+	var first, second time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		second = time.Now()
+		_, _ = w.Write([]byte(groupsBody))
+	}))
+	defer server.Close()
+	client := cloudcontrol.NewClientWithUrl(server.URL, cloudcontrol.WithRetryPolicy(testRetryPolicy))
+
+	_, err := client.GetGroups()
+
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, second.Sub(first), time.Second)
+}
@@ -0,0 +1,69 @@
+package cloudcontrol
+
+import (
+	"context"
+	"github.com/jesper-nord/go-pcc/types"
+)
+
+// Snapshot captures the controllable state of the device, so that it can be put back later with Restore.
+// Horizontal swing is left out if the device does not have it.
+func (d *Device) Snapshot(ctx context.Context) (types.Snapshot, error) {
+	status, err := d.Status(ctx)
+	if err != nil {
+		return types.Snapshot{}, err
+	}
+	info, err := d.Info(ctx)
+	if err != nil {
+		return types.Snapshot{}, err
+	}
+
+	snapshot := types.NewSnapshot(status)
+	snapshot.DeviceGUID = d.guid
+	if !info.Capabilities().AirSwingLR {
+		snapshot.HorizontalSwing = nil
+	}
+	return snapshot, nil
+}
+
+// Restore puts the device back into the state captured by Snapshot, in a single command.
+// The snapshot may have been taken from another device. Horizontal swing is left out
+// if the device does not have it.
+func (d *Device) Restore(ctx context.Context, snapshot types.Snapshot) ([]byte, error) {
+	info, err := d.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Capabilities().AirSwingLR {
+		snapshot.HorizontalSwing = nil
+	}
+	return d.Control().Restore(snapshot).Send(ctx)
+}
+
+// Restore sets all parameters captured in the snapshot. Features that were unavailable
+// when the snapshot was taken are left untouched. Without horizontal swing, fanAutoMode
+// only covers the vertical louvres.
+func (b *CommandBuilder) Restore(snapshot types.Snapshot) *CommandBuilder {
+	b.Power(snapshot.Power).
+		Mode(snapshot.Mode).
+		Temperature(snapshot.Temperature).
+		FanSpeed(snapshot.FanSpeed).
+		EcoMode(snapshot.EcoMode)
+
+	if snapshot.HorizontalSwing != nil {
+		b.HorizontalSwing(*snapshot.HorizontalSwing)
+	} else {
+		b.noHorizontalSwing = true
+	}
+	b.VerticalSwing(snapshot.VerticalSwing)
+
+	if snapshot.Nanoe != types.NanoeUnavailable {
+		b.Nanoe(snapshot.Nanoe)
+	}
+	if snapshot.EcoNavi != types.FeatureUnavailable {
+		b.EcoNavi(snapshot.EcoNavi)
+	}
+	if snapshot.IAuto != types.FeatureUnavailable {
+		b.IAuto(snapshot.IAuto)
+	}
+	return b
+}
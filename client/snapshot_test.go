@@ -0,0 +1,82 @@
+package cloudcontrol_test
+
+import (
+	"context"
+	"encoding/json"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// snapshotHandlers report a device heating at 21.5 degrees with vertical swing and the louvres turned left.
+func snapshotHandlers(recorder *commandRecorder, groups http.HandlerFunc) map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		types.UrlPathGroups:  groups,
+		types.UrlPathControl: recorder.ServeHTTP,
+		types.UrlPathDeviceStatus: func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"parameters":{"operate":1,"operationMode":3,"temperatureSet":21.5,"fanSpeed":2,"fanAutoMode":2,"airSwingUD":0,"airSwingLR":1,"ecoMode":2,"nanoe":2,"ecoNavi":0,"iAuto":0}}`))
+		},
+	}
+}
+
+func TestSnapshot_RestoreReplaysState(t *testing.T) {
+	recorder := &commandRecorder{}
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, snapshotHandlers(recorder, groupsMock)).URL)
+	device := client.Device("CZ-CAPWFC1+B8B7F1B3E326")
+
+	snapshot, err := device.Snapshot(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "CZ-CAPWFC1+B8B7F1B3E326", snapshot.DeviceGUID)
+	assert.Equal(t, types.ModeHeat, snapshot.Mode)
+	assert.Equal(t, types.VerticalSwingAuto, snapshot.VerticalSwing)
+	assert.Equal(t, types.HorizontalSwingLeft, *snapshot.HorizontalSwing)
+
+	data, err := json.Marshal(snapshot)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"operationMode":"heat"`)
+	restored := types.Snapshot{}
+	assert.NoError(t, json.Unmarshal(data, &restored))
+
+	_, err = device.Restore(context.Background(), restored)
+	assert.NoError(t, err)
+	assert.Len(t, recorder.commands, 1)
+	parameters := recorder.commands[0].Parameters
+	assert.Equal(t, int64(types.PowerOn), *parameters.Operate)
+	assert.Equal(t, int64(types.ModeHeat), *parameters.OperationMode)
+	assert.Equal(t, 21.5, *parameters.TemperatureSet)
+	assert.Equal(t, int64(types.FanSpeedLowMid), *parameters.FanSpeed)
+	assert.Equal(t, int64(types.AutoSwingVertical), *parameters.FanAutoMode)
+	assert.Nil(t, parameters.AirSwingUD)
+	assert.Equal(t, int64(types.HorizontalSwingLeft), *parameters.AirSwingLR)
+	assert.Equal(t, int64(types.EcoModeQuiet), *parameters.EcoMode)
+	assert.Equal(t, int64(types.NanoeOn), *parameters.Nanoe)
+	assert.Nil(t, parameters.EcoNavi)
+	assert.Nil(t, parameters.Iauto)
+}
+
+func TestSnapshot_RestoreWithoutHorizontalSwing(t *testing.T) {
+	recorder := &commandRecorder{}
+	server := newTestServer(t, snapshotHandlers(recorder, groupsWithoutSwingLRMock))
+	client := cloudcontrol.NewClientWithUrl(server.URL, cloudcontrol.WithValidation())
+	device := client.Device("CZ-CAPWFC1+B8B7F1B3E326")
+
+	snapshot, err := device.Snapshot(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, snapshot.HorizontalSwing)
+	data, err := json.Marshal(snapshot)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "airSwingLR")
+
+	// a snapshot taken from a device with horizontal swing is restored without it
+	horizontal := types.HorizontalSwingLeft
+	snapshot.HorizontalSwing = &horizontal
+	_, err = device.Restore(context.Background(), snapshot)
+	assert.NoError(t, err)
+	assert.Len(t, recorder.commands, 1)
+	parameters := recorder.commands[0].Parameters
+	assert.Nil(t, parameters.AirSwingLR)
+	assert.Nil(t, parameters.AirSwingUD)
+	assert.Equal(t, int64(types.AutoSwingVertical), *parameters.FanAutoMode)
+}
@@ -0,0 +1,279 @@
+package cloudcontrol
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/labstack/gommon/log"
+	"golang.org/x/crypto/scrypt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrNoToken is returned by a TokenStore that has no session stored.
+var ErrNoToken = errors.New("no stored session token")
+
+// TokenStore persists the session between runs.
+type TokenStore interface {
+	// Load returns the stored session, or ErrNoToken if there is none.
+	Load() (types.Session, error)
+	// Save stores the session, replacing any previous one.
+	Save(session types.Session) error
+}
+
+// WithTokenStore makes the client load its session token from store when created
+// and save every new session to it.
+func WithTokenStore(store TokenStore) Option {
+	return func(c *Client) {
+		c.tokenStore = store
+	}
+}
+
+// loadSession restores the session token from the configured token store.
+func (c *Client) loadSession() {
+	if c.tokenStore == nil || c.Token() != "" {
+		return
+	}
+	session, err := c.tokenStore.Load()
+	if err != nil {
+		if !errors.Is(err, ErrNoToken) {
+			log.Warnf("unable to load session token: %v", err)
+		}
+		return
+	}
+	c.SetToken(session.Utoken)
+}
+
+// saveSession writes a new session to the configured token store.
+func (c *Client) saveSession(session types.Session) {
+	if c.tokenStore == nil {
+		return
+	}
+	if err := c.tokenStore.Save(session); err != nil {
+		log.Warnf("unable to save session token: %v", err)
+	}
+}
+
+// DefaultTokenPath returns the default token cache location, $XDG_CACHE_HOME/go-pcc/token
+// or the platform equivalent.
+func DefaultTokenPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-pcc", "token"), nil
+}
+
+// MemoryTokenStore keeps the session in memory only.
+type MemoryTokenStore struct {
+	mu      sync.Mutex
+	session *types.Session
+}
+
+// Load returns the session saved earlier.
+func (s *MemoryTokenStore) Load() (types.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.session == nil {
+		return types.Session{}, ErrNoToken
+	}
+	return *s.session, nil
+}
+
+// Save keeps the session in memory.
+func (s *MemoryTokenStore) Save(session types.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.session = &session
+	return nil
+}
+
+// FileTokenStore stores the session as JSON in a file only readable by the current user.
+type FileTokenStore struct {
+	Path string
+}
+
+// NewFileTokenStore creates a token store backed by the file at path.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{Path: path}
+}
+
+// Load reads the session from the file.
+func (s *FileTokenStore) Load() (types.Session, error) {
+	data, err := readTokenFile(s.Path)
+	if err != nil {
+		return types.Session{}, err
+	}
+	return unmarshalSession(data)
+}
+
+// Save writes the session to the file.
+func (s *FileTokenStore) Save(session types.Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return writeTokenFile(s.Path, data)
+}
+
+// EncryptedFileTokenStore stores the session in a file encrypted with AES-GCM, using a key
+// derived from a passphrase with scrypt. The scrypt parameters are kept in the file header,
+// so that they can be raised without breaking existing files.
+type EncryptedFileTokenStore struct {
+	Path       string
+	passphrase []byte
+}
+
+const tokenSaltSize = 16
+
+// tokenFileMagic starts an encrypted token file. It is followed by the scrypt cost as log2(N),
+// r and p, one byte each, the salt, the nonce and the sealed session.
+var tokenFileMagic = []byte("GOPCC\x01")
+
+// scryptParams are the scrypt cost parameters of an encrypted token file.
+type scryptParams struct {
+	logN, r, p byte
+}
+
+// defaultScryptParams are the parameters recommended for interactive use.
+var defaultScryptParams = scryptParams{logN: 15, r: 8, p: 1}
+
+// valid reports whether the parameters are within bounds, so that a tampered file
+// cannot make Load spend unbounded time or memory.
+func (p scryptParams) valid() bool {
+	return p.logN >= 10 && p.logN <= 20 && p.r >= 1 && p.r <= 16 && p.p >= 1 && p.p <= 4
+}
+
+// NewEncryptedFileTokenStore creates an encrypted token store backed by the file at path.
+func NewEncryptedFileTokenStore(path string, passphrase string) *EncryptedFileTokenStore {
+	return &EncryptedFileTokenStore{Path: path, passphrase: []byte(passphrase)}
+}
+
+// Load reads and decrypts the session from the file. Files written by earlier versions,
+// without the scrypt header, are treated as if there was no session.
+func (s *EncryptedFileTokenStore) Load() (types.Session, error) {
+	data, err := readTokenFile(s.Path)
+	if err != nil {
+		return types.Session{}, err
+	}
+	if !bytes.HasPrefix(data, tokenFileMagic) {
+		return types.Session{}, fmt.Errorf("%w: token file %s has an outdated format", ErrNoToken, s.Path)
+	}
+	data = data[len(tokenFileMagic):]
+	if len(data) < 3+tokenSaltSize {
+		return types.Session{}, fmt.Errorf("token file %s is truncated", s.Path)
+	}
+
+	params := scryptParams{logN: data[0], r: data[1], p: data[2]}
+	if !params.valid() {
+		return types.Session{}, fmt.Errorf("token file %s has invalid key derivation parameters", s.Path)
+	}
+	data = data[3:]
+	gcm, err := s.cipher(data[:tokenSaltSize], params)
+	if err != nil {
+		return types.Session{}, err
+	}
+	data = data[tokenSaltSize:]
+	if len(data) < gcm.NonceSize() {
+		return types.Session{}, fmt.Errorf("token file %s is truncated", s.Path)
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return types.Session{}, fmt.Errorf("unable to decrypt token file %s: %w", s.Path, err)
+	}
+
+	return unmarshalSession(plain)
+}
+
+// Save encrypts and writes the session to the file.
+func (s *EncryptedFileTokenStore) Save(session types.Session) error {
+	plain, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	params := defaultScryptParams
+	salt := make([]byte, tokenSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	gcm, err := s.cipher(salt, params)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data := append(append([]byte{}, tokenFileMagic...), params.logN, params.r, params.p)
+	data = append(append(data, salt...), nonce...)
+	data = gcm.Seal(data, nonce, plain, nil)
+
+	return writeTokenFile(s.Path, data)
+}
+
+func (s *EncryptedFileTokenStore) cipher(salt []byte, params scryptParams) (cipher.AEAD, error) {
+	key, err := scrypt.Key(s.passphrase, salt, 1<<params.logN, int(params.r), int(params.p), 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func readTokenFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNoToken
+	}
+	return data, err
+}
+
+// writeTokenFile atomically replaces the file at path with data, readable by the owner only.
+func writeTokenFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func unmarshalSession(data []byte) (types.Session, error) {
+	session := types.Session{}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return types.Session{}, fmt.Errorf("invalid token file: %w", err)
+	}
+	if session.Utoken == "" {
+		return types.Session{}, ErrNoToken
+	}
+	return session, nil
+}
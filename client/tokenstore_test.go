@@ -0,0 +1,78 @@
+package cloudcontrol_test
+
+import (
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileTokenStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "go-pcc", "token")
+	store := cloudcontrol.NewFileTokenStore(path)
+
+	_, err := store.Load()
+	assert.ErrorIs(t, err, cloudcontrol.ErrNoToken)
+
+	assert.NoError(t, store.Save(types.Session{Utoken: "token12345"}))
+	session, err := store.Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "token12345", session.Utoken)
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestEncryptedFileTokenStore_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	store := cloudcontrol.NewEncryptedFileTokenStore(path, "correct horse")
+
+	assert.NoError(t, store.Save(types.Session{Utoken: "token12345"}))
+	session, err := store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "token12345", session.Utoken)
+
+	data, _ := os.ReadFile(path)
+	assert.NotContains(t, string(data), "token12345")
+
+	_, err = cloudcontrol.NewEncryptedFileTokenStore(path, "wrong").Load()
+	assert.Error(t, err)
+}
+
+func TestEncryptedFileTokenStore_KeyDerivationHeader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	store := cloudcontrol.NewEncryptedFileTokenStore(path, "correct horse")
+	assert.NoError(t, store.Save(types.Session{Utoken: "token12345"}))
+
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, []byte("GOPCC\x01\x0f\x08\x01"), data[:9])
+
+	// a tampered cost must not be used
+	data[6] = 40
+	assert.NoError(t, os.WriteFile(path, data, 0600))
+	_, err := store.Load()
+	assert.ErrorContains(t, err, "invalid key derivation parameters")
+
+	// files from earlier versions have no header and need a new login
+	assert.NoError(t, os.WriteFile(path, make([]byte, 64), 0600))
+	_, err = store.Load()
+	assert.ErrorIs(t, err, cloudcontrol.ErrNoToken)
+}
+
+func TestWithTokenStore_LoadsAndSavesSession(t *testing.T) {
+	store := &cloudcontrol.MemoryTokenStore{}
+	assert.NoError(t, store.Save(types.Session{Utoken: "stored"}))
+
+	storeClient := cloudcontrol.NewClientWithUrl(client.Server, cloudcontrol.WithTokenStore(store))
+	assert.Equal(t, "stored", storeClient.Utoken)
+
+	_, err := storeClient.CreateSession("user", "pass")
+	assert.NoError(t, err)
+
+	session, _ := store.Load()
+	assert.Equal(t, "token12345", session.Utoken)
+}
@@ -0,0 +1,151 @@
+package cloudcontrol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jesper-nord/go-pcc/types"
+)
+
+// ErrInvalidCommand is matched by every ValidationError.
+var ErrInvalidCommand = errors.New("invalid command")
+
+// ValidationError is returned when a command does not fit the capabilities of the device.
+type ValidationError struct {
+	// Field is the API name of the rejected parameter.
+	Field string
+	// Value is the rejected value.
+	Value any
+	// Reason describes why the value was rejected.
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s %v: %s", e.Field, e.Value, e.Reason)
+}
+
+// Is makes a ValidationError match ErrInvalidCommand.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidCommand
+}
+
+// WithValidation makes the client check every command against the capabilities
+// reported by GetGroups before sending it. A temperature sent without a mode is
+// checked against the range of the mode in the current device status.
+func WithValidation() Option {
+	return func(c *Client) {
+		c.validate = true
+	}
+}
+
+// validate checks the parameters against the capabilities of the device, if validation is enabled.
+func (d *Device) validate(ctx context.Context, parameters types.DeviceControlParameters) error {
+	if !d.client.validate {
+		return nil
+	}
+	info, err := d.Info(ctx)
+	if err != nil {
+		return err
+	}
+	if parameters.TemperatureSet != nil && parameters.OperationMode == nil {
+		// the temperature range depends on the mode, which may have changed since GetGroups
+		status, err := d.Status(ctx)
+		if err != nil {
+			return err
+		}
+		info.Parameters.OperationMode = status.Parameters.OperationMode
+	}
+	return ValidateCommand(info, parameters)
+}
+
+// ValidateCommand checks control parameters against the capabilities of a device
+// as returned by GetGroups, returning a *ValidationError for the first problem found.
+func ValidateCommand(device types.Device, parameters types.DeviceControlParameters) error {
+	capabilities := device.Capabilities()
+
+	mode := device.Parameters.Mode()
+	if parameters.OperationMode != nil {
+		mode = types.OperationMode(*parameters.OperationMode)
+		if err := validateMode(capabilities, mode); err != nil {
+			return err
+		}
+	}
+
+	if parameters.TemperatureSet != nil {
+		if err := validateTemperature(capabilities, mode, *parameters.TemperatureSet); err != nil {
+			return err
+		}
+	}
+
+	if parameters.FanSpeed != nil {
+		maxSpeed := capabilities.FanSpeeds
+		if maxSpeed <= 0 {
+			maxSpeed = 5
+		}
+		if speed := types.FanSpeed(*parameters.FanSpeed); speed < 0 || int64(speed) > maxSpeed {
+			return &ValidationError{"fanSpeed", speed, fmt.Sprintf("device supports auto and 1 to %d", maxSpeed)}
+		}
+	}
+
+	if parameters.EcoMode != nil {
+		if err := validateEcoMode(capabilities, types.EcoMode(*parameters.EcoMode)); err != nil {
+			return err
+		}
+	}
+
+	if !capabilities.AirSwingLR {
+		if parameters.AirSwingLR != nil {
+			return &ValidationError{"airSwingLR", *parameters.AirSwingLR, "device has no horizontal swing"}
+		}
+		if parameters.FanAutoMode != nil {
+			autoMode := types.AutoSwingMode(*parameters.FanAutoMode)
+			if autoMode == types.AutoSwingBoth || autoMode == types.AutoSwingHorizontal {
+				return &ValidationError{"fanAutoMode", autoMode, "device has no horizontal swing"}
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateMode(capabilities types.Capabilities, mode types.OperationMode) error {
+	capability, known := capabilities.Mode(mode)
+	if !known {
+		return &ValidationError{"operationMode", mode, "unknown mode"}
+	}
+	if !capability.Available {
+		return &ValidationError{"operationMode", mode, "mode not supported by device"}
+	}
+	return nil
+}
+
+func validateTemperature(capabilities types.Capabilities, mode types.OperationMode, temperature float64) error {
+	if !types.ValidTemperatureStep(temperature) {
+		return &ValidationError{"temperatureSet", temperature, fmt.Sprintf("must be a multiple of %v degrees", types.TemperatureStep)}
+	}
+
+	capability, _ := capabilities.Mode(mode)
+	if !capability.Temperature.Contains(temperature) {
+		return &ValidationError{"temperatureSet", temperature, fmt.Sprintf("%s mode supports %d to %d degrees",
+			mode, capability.Temperature.Min, capability.Temperature.Max)}
+	}
+	return nil
+}
+
+func validateEcoMode(capabilities types.Capabilities, mode types.EcoMode) error {
+	switch mode {
+	case types.EcoModeAuto:
+		return nil
+	case types.EcoModePowerful:
+		if !capabilities.PowerfulMode {
+			return &ValidationError{"ecoMode", mode, "device has no powerful mode"}
+		}
+		return nil
+	case types.EcoModeQuiet:
+		if !capabilities.QuietMode {
+			return &ValidationError{"ecoMode", mode, "device has no quiet mode"}
+		}
+		return nil
+	}
+	return &ValidationError{"ecoMode", mode, "unknown eco mode"}
+}
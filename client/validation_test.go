@@ -0,0 +1,98 @@
+package cloudcontrol_test
+
+import (
+	"context"
+	"errors"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidation_RejectsBeforeSending(t *testing.T) {
+	recorder := &commandRecorder{}
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, capabilityHandlers(recorder)).URL, cloudcontrol.WithValidation())
+	device := client.Device("CZ-CAPWFC1+B8B7F1B3E326")
+
+	tests := []struct {
+		name    string
+		command *cloudcontrol.CommandBuilder
+		field   string
+	}{
+		{"temperature above range", device.Control().Temperature(35), "temperatureSet"},
+		{"temperature between steps", device.Control().Temperature(21.3), "temperatureSet"},
+		{"unknown mode", device.Control().Mode(9), "operationMode"},
+		{"fan speed above maximum", device.Control().FanSpeed(6), "fanSpeed"},
+		{"unknown eco mode", device.Control().EcoMode(7), "ecoMode"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := test.command.Send(context.Background())
+
+			var validationErr *cloudcontrol.ValidationError
+			assert.ErrorIs(t, err, cloudcontrol.ErrInvalidCommand)
+			assert.True(t, errors.As(err, &validationErr))
+			assert.Equal(t, test.field, validationErr.Field)
+		})
+	}
+	assert.Empty(t, recorder.commands)
+}
+
+func TestValidation_AcceptsValidCommand(t *testing.T) {
+	recorder := &commandRecorder{}
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, capabilityHandlers(recorder)).URL, cloudcontrol.WithValidation())
+
+	_, err := client.Device("CZ-CAPWFC1+B8B7F1B3E326").Control().
+		Mode(types.ModeHeat).Temperature(21.5).FanSpeed(5).EcoMode(types.EcoModeQuiet).
+		Send(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(recorder.commands))
+}
+
+func TestValidation_ModeFromModeAvailability(t *testing.T) {
+	recorder := &commandRecorder{}
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, capabilityHandlers(recorder)).URL, cloudcontrol.WithValidation())
+
+	// the fixture reports fanMode false, but fan mode in its modeAvlList
+	_, err := client.Device("CZ-CAPWFC1+B8B7F1B3E326").Control().Mode(types.ModeFan).Send(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(recorder.commands))
+}
+
+func TestValidateCommand_UnsupportedMode(t *testing.T) {
+	device := types.Device{HeatMode: true, ModeAvailability: types.ModeAvailability{AutoMode: true}}
+	fan := int64(types.ModeFan)
+
+	err := cloudcontrol.ValidateCommand(device, types.DeviceControlParameters{OperationMode: &fan})
+
+	assert.EqualError(t, err, "invalid operationMode fan: mode not supported by device")
+}
+
+func TestValidateCommand_TemperatureRangeFollowsMode(t *testing.T) {
+	device := types.Device{
+		HeatMode: true, CoolMode: true,
+		HeatTempMin: 16, HeatTempMax: 30,
+		CoolTempMin: 18, CoolTempMax: 30,
+	}
+	temperature := 17.0
+	heat, cool := int64(types.ModeHeat), int64(types.ModeCool)
+
+	assert.NoError(t, cloudcontrol.ValidateCommand(device, types.DeviceControlParameters{OperationMode: &heat, TemperatureSet: &temperature}))
+	assert.Error(t, cloudcontrol.ValidateCommand(device, types.DeviceControlParameters{OperationMode: &cool, TemperatureSet: &temperature}))
+}
+
+func TestValidation_TemperatureForCurrentMode(t *testing.T) {
+	recorder := &commandRecorder{}
+	client := cloudcontrol.NewClientWithUrl(newTestServer(t, capabilityHandlers(recorder)).URL, cloudcontrol.WithValidation())
+	device := client.Device("CZ-CAPWFC1+B8B7F1B3E326")
+
+	// the fixture is in auto mode (17 to 27 degrees), the status in heat mode (16 to 30 degrees)
+	_, heatErr := device.Control().Temperature(29).Send(context.Background())
+	_, autoErr := device.Control().Mode(types.ModeAuto).Temperature(29).Send(context.Background())
+
+	assert.NoError(t, heatErr)
+	assert.ErrorIs(t, autoErr, cloudcontrol.ErrInvalidCommand)
+	assert.Equal(t, 1, len(recorder.commands))
+}
@@ -0,0 +1,652 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/labstack/gommon/log"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// command is a go-pcc subcommand.
+type command struct {
+	name    string
+	args    string
+	summary string
+	// csv tells whether the output of the command can be written as csv.
+	csv bool
+	// setup registers the flags of the command and returns the function running it.
+	setup func(fs *flag.FlagSet) func(ctx context.Context, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"devices", "", "List available devices", true, devicesCommand},
+		{"status", "", "Display current status of device", true, statusCommand},
+		{"set", "[settings]", "Change settings of device", false, setCommand},
+		{"history", "day|week|month|year", "Display history of device", true, historyCommand},
+		{"snapshot", "save|restore <file>", "Save state of device to a file, or restore it", false, snapshotCommand},
+		{"boost", "-for <duration> [settings]", "Change settings of device for a limited time", false, boostCommand},
+		{"login", "", "Log in with the configured credentials and cache the session token", false, loginCommand},
+		{"config", "", "Display the configuration in use", false, configCommand},
+		{"help", "[command]", "Display help for a command", false, helpCommand},
+	}
+}
+
+func lookupCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// usage prints the list of commands and the global flags.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: go-pcc [flags] <command> [arguments]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nRun 'go-pcc help <command>' for the arguments and flags of a command.")
+	fmt.Fprintln(w, "\nFlags:")
+	fs := flag.NewFlagSet("go-pcc", flag.ContinueOnError)
+	registerGlobalFlags(fs)
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// commandUsage prints the arguments and flags of a command.
+func commandUsage(w io.Writer, cmd command, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage: go-pcc %s [flags] %s\n\n%s.\n\nFlags:\n", cmd.name, cmd.args, cmd.summary)
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+func helpCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		switch len(args) {
+		case 0:
+			usage(os.Stdout)
+			return nil
+		case 1:
+			cmd, ok := lookupCommand(args[0])
+			if !ok {
+				return usageError(fmt.Sprintf("unknown command %q", args[0]))
+			}
+			cfs := flag.NewFlagSet("go-pcc "+cmd.name, flag.ContinueOnError)
+			registerGlobalFlags(cfs)
+			cmd.setup(cfs)
+			commandUsage(os.Stdout, cmd, cfs)
+			return nil
+		}
+		return usageError("usage: go-pcc help [command]")
+	}
+}
+
+func devicesCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	allProfiles := fs.Bool("all-profiles", false, "List the devices of every profile in the config file")
+	return func(ctx context.Context, args []string) error {
+		if len(args) > 0 {
+			return usageError("usage: go-pcc devices")
+		}
+		if *allProfiles {
+			return devicesOfProfiles(ctx)
+		}
+		client, err := connect(ctx)
+		if err != nil {
+			return err
+		}
+
+		log.Info("listing available devices")
+		summaries, err := client.DeviceSummariesContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		if len(summaries) == 0 {
+			return errors.New("found no devices for configured account")
+		}
+
+		log.Infof("%d device(s) found:\n", len(summaries))
+		return render("devices", deviceList(summaries), func(w io.Writer) {
+			writeDeviceTable(w, summaries)
+		})
+	}
+}
+
+// devicesOfProfiles lists the devices of every profile, logging in to each account in turn. The
+// account of the top level settings is listed first, with an empty profile name, if it has a username.
+// Profiles that fail are reported after the devices of the others.
+func devicesOfProfiles(ctx context.Context) error {
+	if err := readConfig(); err != nil {
+		return err
+	}
+	names := profiles()
+	if credentials("").Username != "" {
+		names = append([]string{""}, names...)
+	}
+	if len(names) == 0 {
+		return usageError("no accounts in config file")
+	}
+
+	devices := profileDeviceList{}
+	summaries := map[string][]types.DeviceSummary{}
+	var errs []error
+	for _, name := range names {
+		log.Infof("listing devices of %s", profileLabel(name))
+		client, err := connectProfile(ctx, name)
+		if err == nil {
+			summaries[name], err = client.DeviceSummariesContext(ctx)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", profileLabel(name), err))
+			continue
+		}
+		for _, summary := range summaries[name] {
+			devices = append(devices, profileDevice{Profile: name, DeviceSummary: summary})
+		}
+	}
+
+	err := render("devices", devices, func(w io.Writer) {
+		for _, name := range names {
+			if len(summaries[name]) > 0 {
+				fmt.Fprintf(w, "%s:\n", profileLabel(name))
+				writeDeviceTable(w, summaries[name])
+				fmt.Fprintln(w)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// profileLabel names a profile in messages, the top level settings being the default profile.
+func profileLabel(name string) string {
+	if name == "" {
+		return "default profile"
+	}
+	return "profile " + name
+}
+
+func statusCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if len(args) > 0 {
+			return usageError("usage: go-pcc status")
+		}
+		client, err := connect(ctx)
+		if err != nil {
+			return err
+		}
+		devices, err := selectDevices(ctx, client)
+		if err != nil {
+			return err
+		}
+
+		log.Info("fetching device status")
+		fetch := func(ctx context.Context, device *cloudcontrol.Device) (any, error) {
+			status, err := device.Status(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return newStatusOutput(device.GUID(), status), nil
+		}
+		return forEachDevice(ctx, client, devices, "status", statusOutput{}, fetch, func(w io.Writer, result any) {
+			writeStatus(w, result.(statusOutput))
+		})
+	}
+}
+
+// writeStatus writes the status of a device as text.
+func writeStatus(w io.Writer, status statusOutput) {
+	fmt.Fprintf(w, "Status: %s\n", status.Power)
+	fmt.Fprintf(w, "Mode: %s\n", status.Mode)
+	fmt.Fprintf(w, "Temperature: %0.1f\n", status.TemperatureSet)
+	fmt.Fprintf(w, "Outside temperature: %s\n", formatTemperature(status.OutsideTemperature))
+	fmt.Fprintf(w, "Fan speed: %s\n", status.FanSpeed)
+	fmt.Fprintf(w, "Eco mode: %s\n", status.EcoMode)
+	fmt.Fprintf(w, "Vertical swing: %s\n", status.VerticalSwing)
+	fmt.Fprintf(w, "Horizontal swing: %s\n", status.HorizontalSwing)
+	fmt.Fprintf(w, "nanoe: %s\n", status.Nanoe)
+	fmt.Fprintf(w, "ecoNavi: %s\n", status.EcoNavi)
+	fmt.Fprintf(w, "iAuto-X: %s\n", status.IAutoX)
+}
+
+// setOptions are the flags of set besides the settings.
+type setOptions struct {
+	settings   *settings
+	conditions conditionsFlag
+	wait       time.Duration
+}
+
+func newSetOptions(fs *flag.FlagSet, prefix string) *setOptions {
+	opts := &setOptions{settings: newSettings(fs, prefix)}
+	fs.Var(&opts.conditions, "if", prefix+"Only send changes if the device is in this state, e.g. temp=19,mode=heat")
+	fs.DurationVar(&opts.wait, "wait", 0, prefix+"Wait up to this long (e.g. 30s) for the device to confirm the changes")
+	return opts
+}
+
+func setCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	opts := newSetOptions(fs, "")
+	return func(ctx context.Context, args []string) error {
+		if len(args) > 0 {
+			return usageError(fmt.Sprintf("unexpected argument %q, settings are given as flags", args[0]))
+		}
+		return set(ctx, opts)
+	}
+}
+
+// set sends all settings to each selected device in one command.
+func set(ctx context.Context, opts *setOptions) error {
+	if opts.settings.empty() {
+		return usageError("nothing to set, see 'go-pcc help set'")
+	}
+	client, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	devices, err := selectDevices(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	send := func(ctx context.Context, device *cloudcontrol.Device) (any, error) {
+		return setDevice(ctx, device, opts)
+	}
+	return forEachDevice(ctx, client, devices, "change", nil, send, func(w io.Writer, result any) {
+		writeChanges(w, result.(changeOutput))
+	})
+}
+
+// writeChanges writes the changes made to a device as text, followed by the parameters
+// the device confirmed when waiting for it.
+func writeChanges(w io.Writer, result changeOutput) {
+	for _, change := range result.Changes {
+		fmt.Fprintln(w, change)
+	}
+	if len(result.Applied) > 0 {
+		fmt.Fprintf(w, "confirmed: %s\n", strings.Join(result.Applied, ", "))
+	}
+}
+
+// setDevice sends the settings to a device.
+func setDevice(ctx context.Context, device *cloudcontrol.Device, opts *setOptions) (changeOutput, error) {
+	command := device.Control()
+	changes, err := opts.settings.apply(command)
+	if err != nil {
+		return changeOutput{}, err
+	}
+	command.If(opts.conditions...)
+
+	result := changeOutput{GUID: device.GUID(), Changes: changes}
+	log.Infof("sending command to %s: %s", device.GUID(), strings.Join(changes, ", "))
+	if opts.wait > 0 {
+		confirmation, err := command.SendAndConfirm(ctx, cloudcontrol.ConfirmOptions{Timeout: opts.wait})
+		if err != nil {
+			if len(confirmation.Applied) > 0 {
+				log.Warnf("applied before failing: %s", strings.Join(confirmation.Applied, ", "))
+			}
+			return changeOutput{}, err
+		}
+		result.Applied = confirmation.Applied
+	} else if _, err := command.Send(ctx); err != nil {
+		return changeOutput{}, err
+	}
+	return result, nil
+}
+
+func historyCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 1 {
+			return usageError("usage: go-pcc history day|week|month|year")
+		}
+		period, err := types.ParseHistoryPeriod(args[0])
+		if err != nil {
+			return usageError(err.Error())
+		}
+		return history(ctx, period)
+	}
+}
+
+// history prints the history of the device.
+func history(ctx context.Context, period types.HistoryPeriod) error {
+	client, err := connect(ctx)
+	if err != nil {
+		return err
+	}
+	device, err := selectDevice(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("fetching historical data for %s\n", period)
+	history, err := device.History(ctx, period)
+	if err != nil {
+		return err
+	}
+	return render("history", newHistoryOutput(device.GUID(), period, history), func(w io.Writer) {
+		fmt.Fprintln(w, "#,AverageSettingTemp,AverageOutsideTemp,Consumption")
+		for _, v := range history.HistoryEntries {
+			fmt.Fprintf(w, "%v,%v,%v,%v\n", v.DataNumber+1, v.AverageSettingTemp, v.AverageOutsideTemp, v.Consumption)
+		}
+	})
+}
+
+// snapshotCommand saves the state of the device to a file, or restores it from one.
+// A restored snapshot goes to the device it was taken from, unless -device is given.
+func snapshotCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if len(args) != 2 || (args[0] != "save" && args[0] != "restore") {
+			return usageError("usage: go-pcc snapshot save|restore <file>")
+		}
+		path := args[1]
+
+		client, err := connect(ctx)
+		if err != nil {
+			return err
+		}
+
+		if args[0] == "save" {
+			device, err := selectDevice(ctx, client)
+			if err != nil {
+				return err
+			}
+			log.Infof("saving snapshot of %s", device.GUID())
+			snapshot, err := device.Snapshot(ctx)
+			if err != nil {
+				return err
+			}
+			data, _ := json.MarshalIndent(snapshot, "", "  ")
+			if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+				return err
+			}
+			return render("snapshot", snapshot, func(w io.Writer) {
+				fmt.Fprintf(w, "snapshot of %s saved to %s\n", device.GUID(), path)
+			})
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		snapshot := types.Snapshot{}
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return fmt.Errorf("invalid snapshot %s: %w", path, err)
+		}
+		device := client.Device(snapshot.DeviceGUID)
+		if len(deviceFlag) > 0 || snapshot.DeviceGUID == "" {
+			if device, err = selectDevice(ctx, client); err != nil {
+				return err
+			}
+		}
+
+		log.Infof("restoring snapshot from %s to %s", snapshot.Time.Local().Format(time.RFC3339), device.GUID())
+		if _, err := device.Restore(ctx, snapshot); err != nil {
+			return err
+		}
+		snapshot.DeviceGUID = device.GUID()
+		return render("snapshot", snapshot, func(w io.Writer) {
+			fmt.Fprintf(w, "snapshot %s restored to %s\n", path, device.GUID())
+		})
+	}
+}
+
+// boostCommand applies settings for a limited time and then reverts the device to its previous state.
+// The pending revert is saved, so that a later invocation reverts it if this one is stopped.
+func boostCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	duration := fs.Duration("for", 0, "How long to boost, e.g. 30m")
+	cancel := fs.Bool("cancel", false, "Revert a pending boost right away")
+	detach := fs.Bool("detach", false, "Return right away, leaving the revert to a later invocation")
+	settings := newSettings(fs, "")
+
+	return func(ctx context.Context, args []string) error {
+		if len(args) > 0 {
+			return usageError(fmt.Sprintf("unexpected argument %q, settings are given as flags", args[0]))
+		}
+		if !*cancel && *duration <= 0 {
+			return usageError("usage: go-pcc boost -for <duration> [settings]")
+		}
+
+		client, err := connect(ctx)
+		if err != nil {
+			return err
+		}
+		device, err := selectDevice(ctx, client)
+		if err != nil {
+			return err
+		}
+		store, err := boostStore(profile)
+		if err != nil {
+			return err
+		}
+
+		if *cancel {
+			cancelled, err := device.CancelBoost(ctx, store)
+			if err != nil {
+				return err
+			}
+			if !cancelled {
+				return fmt.Errorf("no pending boost for %s", device.GUID())
+			}
+			return render("boost", struct {
+				GUID      string `json:"guid"`
+				Cancelled bool   `json:"cancelled"`
+			}{device.GUID(), true}, func(w io.Writer) {
+				fmt.Fprintf(w, "boost of %s cancelled, previous state restored\n", device.GUID())
+			})
+		}
+
+		command := device.Control()
+		if !settings.off {
+			command.On()
+		}
+		if _, err := settings.apply(command); err != nil {
+			return err
+		}
+
+		pending, err := command.Boost(ctx, *duration, store)
+		if err != nil {
+			return err
+		}
+		err = render("boost", pending, func(w io.Writer) {
+			fmt.Fprintf(w, "%s boosted until %s\n", device.GUID(), pending.Expires.Local().Format(time.Kitchen))
+		})
+		if err != nil || *detach {
+			return err
+		}
+
+		log.Infof("waiting %s to revert, interrupt to leave the revert to a later invocation", *duration)
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		select {
+		case <-time.After(time.Until(pending.Expires)):
+			if err := revertExpiredBoosts(ctx, client, profile); err != nil {
+				return fmt.Errorf("unable to revert boost: %w", err)
+			}
+		case <-interrupt:
+			log.Info("interrupted, the boost will be reverted by the first invocation after it expires")
+		}
+		return nil
+	}
+}
+
+// devicesError is returned when a command failed for some of several devices.
+type devicesError struct {
+	total int
+	errs  []error
+}
+
+func (e *devicesError) Error() string {
+	return fmt.Sprintf("command failed for %d of %d devices", len(e.errs), e.total)
+}
+
+func (e *devicesError) Unwrap() []error {
+	return e.errs
+}
+
+// forEachDevice runs fn for all devices concurrently and renders the outcome per device as a
+// document of the given kind, listing the devices whatever their number. In csv output, the
+// columns of each result follow the summary of its device, columns is the zero value of the
+// results or nil if they have no columns. In text output, text writes the result of each device
+// that succeeded, followed by a summary if there are several devices.
+func forEachDevice(ctx context.Context, client *cloudcontrol.Client, devices []types.Device, kind string, columns table,
+	fn func(ctx context.Context, device *cloudcontrol.Device) (any, error), text func(w io.Writer, result any)) error {
+	guids := make([]string, len(devices))
+	indexes := map[string]int{}
+	for i, device := range devices {
+		guids[i] = device.DeviceGUID
+		indexes[device.DeviceGUID] = i
+	}
+
+	results := make(resultList, len(devices))
+	errs := client.ForEachDevice(ctx, guids, func(ctx context.Context, device *cloudcontrol.Device) error {
+		result, err := fn(ctx, device)
+		results[indexes[device.GUID()]].Result = result
+		return err
+	})
+
+	var failed []error
+	for i, device := range devices {
+		results[i].GUID, results[i].Name = device.DeviceGUID, device.DeviceName
+		results[i].OK = errs[i] == nil
+		if errs[i] != nil {
+			results[i].Error = errs[i].Error()
+			results[i].Result = nil
+			failed = append(failed, errs[i])
+		}
+	}
+
+	err := render(kind, resultTable{results: results, columns: columns}, func(w io.Writer) {
+		if len(results) == 1 {
+			if results[0].OK {
+				text(w, results[0].Result)
+			}
+			return
+		}
+		for _, result := range results {
+			if result.OK {
+				fmt.Fprintf(w, "%s (%s):\n", result.Name, result.GUID)
+				text(w, result.Result)
+				fmt.Fprintln(w)
+			}
+		}
+		writeResultTable(w, results)
+	})
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(failed) == 0:
+		return nil
+	case len(devices) == 1:
+		return failed[0]
+	}
+	return &devicesError{total: len(devices), errs: failed}
+}
+
+// revertExpiredBoosts restores the devices of a profile whose boost has expired.
+func revertExpiredBoosts(ctx context.Context, client *cloudcontrol.Client, name string) error {
+	store, err := boostStore(name)
+	if err != nil {
+		return err
+	}
+	reverted, err := client.RevertExpiredBoosts(ctx, store)
+	for _, boost := range reverted {
+		log.Infof("boost of %s expired, previous state restored", boost.DeviceGUID)
+	}
+	return err
+}
+
+func loginCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if len(args) > 0 {
+			return usageError("usage: go-pcc login")
+		}
+		if err := readConfig(); err != nil {
+			return err
+		}
+		client, err := newClient(profile)
+		if err != nil {
+			return err
+		}
+		if err := login(ctx, client, profile); err != nil {
+			return err
+		}
+		username := credentials(profile).Username
+		return render("login", struct {
+			Username string `json:"username"`
+		}{username}, func(w io.Writer) {
+			fmt.Fprintf(w, "logged in as %s\n", username)
+		})
+	}
+}
+
+func configCommand(fs *flag.FlagSet) func(ctx context.Context, args []string) error {
+	return func(ctx context.Context, args []string) error {
+		if len(args) > 0 {
+			return usageError("usage: go-pcc config")
+		}
+		if err := readConfig(); err != nil {
+			return err
+		}
+
+		token, err := tokenPath(profile)
+		if err != nil {
+			return err
+		}
+		config := configOutput{
+			ConfigFile:     viper.ConfigFileUsed(),
+			Profile:        profile,
+			Profiles:       profiles(),
+			Username:       viper.GetString(configKey("username")),
+			PasswordSet:    viper.GetString(configKey("password")) != "",
+			Device:         viper.GetString(configKey("device")),
+			Aliases:        viper.GetStringMapString(configKey("aliases")),
+			TokenFile:      token,
+			TokenEncrypted: os.Getenv("GO_PCC_TOKEN_PASSPHRASE") != "",
+		}
+
+		return render("config", config, func(w io.Writer) {
+			password := "(not set)"
+			if config.PasswordSet {
+				password = "(set)"
+			}
+			fmt.Fprintf(w, "Config file: %s\n", config.ConfigFile)
+			if config.Profile != "" {
+				fmt.Fprintf(w, "Profile: %s\n", config.Profile)
+			}
+			if len(config.Profiles) > 0 {
+				fmt.Fprintf(w, "Profiles: %s\n", strings.Join(config.Profiles, ", "))
+			}
+			fmt.Fprintf(w, "Username: %s\n", config.Username)
+			fmt.Fprintf(w, "Password: %s\n", password)
+			fmt.Fprintf(w, "Device: %s\n", config.Device)
+			var aliases []string
+			for alias := range config.Aliases {
+				aliases = append(aliases, alias)
+			}
+			sort.Strings(aliases)
+			for _, alias := range aliases {
+				fmt.Fprintf(w, "Alias: %s = %s\n", alias, config.Aliases[alias])
+			}
+			fmt.Fprintf(w, "Token file: %s\n", config.TokenFile)
+			fmt.Fprintf(w, "Token encrypted: %t\n", config.TokenEncrypted)
+		})
+	}
+}
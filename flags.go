@@ -0,0 +1,205 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"strconv"
+	"strings"
+)
+
+// enumFlag is a flag for one of the enums in types. The value is parsed when the flag
+// is given, so unknown values are rejected together with the other usage errors.
+type enumFlag[T fmt.Stringer] struct {
+	value T
+	set   bool
+	parse func(string) (T, error)
+}
+
+func newEnumFlag[T fmt.Stringer](parse func(string) (T, error)) *enumFlag[T] {
+	return &enumFlag[T]{parse: parse}
+}
+
+func (f *enumFlag[T]) String() string {
+	if f == nil || !f.set {
+		return ""
+	}
+	return f.value.String()
+}
+
+func (f *enumFlag[T]) Set(s string) error {
+	value, err := f.parse(s)
+	if err != nil {
+		return err
+	}
+	f.value, f.set = value, true
+	return nil
+}
+
+// floatFlag is a float flag that tells whether it was given, so that 0 is a valid value.
+type floatFlag struct {
+	value float64
+	set   bool
+}
+
+func (f *floatFlag) String() string {
+	if f == nil || !f.set {
+		return ""
+	}
+	return strconv.FormatFloat(f.value, 'f', -1, 64)
+}
+
+func (f *floatFlag) Set(s string) error {
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	f.value, f.set = value, true
+	return nil
+}
+
+// conditionsFlag collects the conditions of -if, which may be given more than once.
+type conditionsFlag []cloudcontrol.Condition
+
+func (f *conditionsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	var terms []string
+	for _, condition := range *f {
+		terms = append(terms, condition.String())
+	}
+	return strings.Join(terms, ",")
+}
+
+func (f *conditionsFlag) Set(s string) error {
+	conditions, err := cloudcontrol.ParseConditions(s)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, conditions...)
+	return nil
+}
+
+// settings are the flags changing the state of a device, used by set and boost.
+type settings struct {
+	on              bool
+	off             bool
+	temperature     floatFlag
+	mode            *enumFlag[types.OperationMode]
+	fanSpeed        *enumFlag[types.FanSpeed]
+	ecoMode         *enumFlag[types.EcoMode]
+	verticalSwing   *enumFlag[types.VerticalSwing]
+	horizontalSwing *enumFlag[types.HorizontalSwing]
+	nanoe           *enumFlag[types.NanoeMode]
+	ecoNavi         *enumFlag[types.FeatureState]
+	iAuto           *enumFlag[types.FeatureState]
+}
+
+// newSettings registers the settings flags, their usage prefixed with the given text.
+func newSettings(fs *flag.FlagSet, prefix string) *settings {
+	s := &settings{
+		mode:            newEnumFlag(types.ParseOperationMode),
+		fanSpeed:        newEnumFlag(types.ParseFanSpeed),
+		ecoMode:         newEnumFlag(types.ParseEcoMode),
+		verticalSwing:   newEnumFlag(types.ParseVerticalSwing),
+		horizontalSwing: newEnumFlag(types.ParseHorizontalSwing),
+		nanoe:           newEnumFlag(types.ParseNanoeMode),
+		ecoNavi:         newEnumFlag(types.ParseFeatureState),
+		iAuto:           newEnumFlag(types.ParseFeatureState),
+	}
+	fs.BoolVar(&s.on, "on", false, prefix+"Turn device on")
+	fs.BoolVar(&s.off, "off", false, prefix+"Turn device off")
+	fs.Var(&s.temperature, "temp", prefix+"Set the temperature (in Celsius)")
+	fs.Var(s.mode, "mode", prefix+"Set mode: auto,heat,cool,dry,fan")
+	fs.Var(s.fanSpeed, "speed", prefix+"Set fan speed: auto,1,2,3,4,5")
+	fs.Var(s.ecoMode, "ecomode", prefix+"Set eco mode: auto,powerful,quiet")
+	fs.Var(s.verticalSwing, "swing-ud", prefix+"Set vertical air swing: auto,up,up-mid,mid,down-mid,down")
+	fs.Var(s.horizontalSwing, "swing-lr", prefix+"Set horizontal air swing: auto,left,left-mid,mid,right-mid,right")
+	fs.Var(s.nanoe, "nanoe", prefix+"Set nanoe: on,off,mode-g,all")
+	fs.Var(s.ecoNavi, "econavi", prefix+"Set ecoNavi: on,off")
+	fs.Var(s.iAuto, "iauto", prefix+"Set iAuto-X: on,off")
+	return s
+}
+
+// empty reports whether no setting was given.
+func (s *settings) empty() bool {
+	return !s.on && !s.off && !s.temperature.set && !s.mode.set && !s.fanSpeed.set && !s.ecoMode.set &&
+		!s.verticalSwing.set && !s.horizontalSwing.set && !s.nanoe.set && !s.ecoNavi.set && !s.iAuto.set
+}
+
+// apply adds the given settings to the command and describes the changes.
+func (s *settings) apply(command *cloudcontrol.CommandBuilder) ([]string, error) {
+	var changes []string
+
+	if s.on && s.off {
+		return nil, usageError("-on and -off cannot be combined")
+	}
+	if s.on {
+		command.On()
+		changes = append(changes, "device turned on")
+	}
+	if s.off {
+		command.Off()
+		changes = append(changes, "device turned off")
+	}
+	if s.temperature.set {
+		command.Temperature(s.temperature.value)
+		changes = append(changes, fmt.Sprintf("temperature set to %v degrees", s.temperature.value))
+	}
+	if s.fanSpeed.set {
+		command.FanSpeed(s.fanSpeed.value)
+		changes = append(changes, fmt.Sprintf("fan speed set to %s", s.fanSpeed.value))
+	}
+	if s.mode.set {
+		command.Mode(s.mode.value)
+		changes = append(changes, fmt.Sprintf("mode set to %s", s.mode.value))
+	}
+	if s.ecoMode.set {
+		command.EcoMode(s.ecoMode.value)
+		changes = append(changes, fmt.Sprintf("eco mode set to %s", s.ecoMode.value))
+	}
+	if s.verticalSwing.set {
+		command.VerticalSwing(s.verticalSwing.value)
+		changes = append(changes, fmt.Sprintf("vertical swing set to %s", s.verticalSwing.value))
+	}
+	if s.horizontalSwing.set {
+		command.HorizontalSwing(s.horizontalSwing.value)
+		changes = append(changes, fmt.Sprintf("horizontal swing set to %s", s.horizontalSwing.value))
+	}
+	if s.nanoe.set {
+		command.Nanoe(s.nanoe.value)
+		changes = append(changes, fmt.Sprintf("nanoe set to %s", s.nanoe.value))
+	}
+	if s.ecoNavi.set {
+		command.EcoNavi(s.ecoNavi.value)
+		changes = append(changes, fmt.Sprintf("ecoNavi set to %s", s.ecoNavi.value))
+	}
+	if s.iAuto.set {
+		command.IAuto(s.iAuto.value)
+		changes = append(changes, fmt.Sprintf("iAuto-X set to %s", s.iAuto.value))
+	}
+
+	return changes, nil
+}
+
+// devicesFlag collects the device selectors of -device, which may be given more than once
+// or as a comma separated list.
+type devicesFlag []string
+
+func (f *devicesFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, ",")
+}
+
+func (f *devicesFlag) Set(s string) error {
+	for _, selector := range strings.Split(s, ",") {
+		if selector = strings.TrimSpace(selector); selector != "" {
+			*f = append(*f, selector)
+		}
+	}
+	return nil
+}
@@ -6,6 +6,8 @@ require (
 	github.com/labstack/gommon v0.4.0
 	github.com/spf13/viper v1.17.0
 	github.com/stretchr/testify v1.8.4
+	golang.org/x/crypto v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -32,5 +34,4 @@ require (
 	golang.org/x/sys v0.13.0 // indirect
 	golang.org/x/text v0.13.0 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
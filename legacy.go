@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/labstack/gommon/log"
+	"strings"
+)
+
+// deprecated prefixes the usage of the flags kept from before the commands were introduced.
+const deprecated = "Deprecated: "
+
+// legacyFlags are the top level flags from before the commands were introduced.
+type legacyFlags struct {
+	list    bool
+	status  bool
+	history *enumFlag[types.HistoryPeriod]
+	set     *setOptions
+}
+
+func newLegacyFlags(fs *flag.FlagSet) *legacyFlags {
+	legacy := &legacyFlags{history: newEnumFlag(types.ParseHistoryPeriod)}
+	fs.BoolVar(&legacy.list, "list", false, deprecated+"use 'go-pcc devices'")
+	fs.BoolVar(&legacy.status, "status", false, deprecated+"use 'go-pcc status'")
+	fs.Var(legacy.history, "history", deprecated+"use 'go-pcc history'")
+	legacy.set = newSetOptions(fs, deprecated+"use 'go-pcc set'. ")
+	return legacy
+}
+
+// legacyUsed reports whether any of the deprecated flags was given.
+func legacyUsed(fs *flag.FlagSet) bool {
+	used := false
+	fs.Visit(func(f *flag.Flag) {
+		if isDeprecated(f) {
+			used = true
+		}
+	})
+	return used
+}
+
+func isDeprecated(f *flag.Flag) bool {
+	return strings.HasPrefix(f.Usage, deprecated)
+}
+
+// runLegacy runs the commands selected by the deprecated flags, in the order of earlier versions.
+func runLegacy(ctx context.Context, l *legacyFlags) error {
+	if l.list {
+		log.Warn("-list is deprecated, use 'go-pcc devices'")
+		return devicesCommand(flag.NewFlagSet("devices", flag.ContinueOnError))(ctx, nil)
+	}
+
+	if l.status {
+		log.Warn("-status is deprecated, use 'go-pcc status'")
+		if err := statusCommand(nil)(ctx, nil); err != nil {
+			return err
+		}
+	}
+
+	if l.history.set {
+		log.Warn("-history is deprecated, use 'go-pcc history'")
+		if err := history(ctx, l.history.value); err != nil {
+			return err
+		}
+	}
+
+	if !l.set.settings.empty() {
+		log.Warn("setting flags without a command are deprecated, use 'go-pcc set'")
+		return set(ctx, l.set)
+	}
+	return nil
+}
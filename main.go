@@ -1,211 +1,512 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	cloudcontrol "github.com/jesper-nord/go-pcc/client"
 	"github.com/jesper-nord/go-pcc/types"
 	"github.com/labstack/gommon/log"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
+// Exit codes, one per class of error.
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitAuth        = 3
+	exitOffline     = 4
+	exitRateLimited = 5
+	exitInvalid     = 6
+	exitConflict    = 7
+)
+
+// Global flags, accepted before and after the command name. See output.go for -output.
 var (
-	configFlag   = flag.String("config", "./go-pcc.yaml", "Path of YAML configuration file")
-	debugFlag    = flag.Bool("debug", false, "Show debug output")
-	deviceFlag   = flag.String("device", "", "Device to issue command to")
-	historyFlag  = flag.String("history", "", "Display history: day,week,month,year")
-	listFlag     = flag.Bool("list", false, "List available devices")
-	modeFlag     = flag.String("mode", "", "Set mode: auto,heat,cool,dry,fan")
-	ecoModeFlag  = flag.String("ecomode", "", "Set eco mode: auto,powerful,quiet")
-	offFlag      = flag.Bool("off", false, "Turn device off")
-	onFlag       = flag.Bool("on", false, "Turn device on")
-	suppressFlag = flag.Bool("suppress", false, "Suppress log messages")
-	statusFlag   = flag.Bool("status", false, "Display current status of device")
-	tempFlag     = flag.Float64("temp", 0, "Set the temperature (in Celsius)")
-	fanSpeedFlag = flag.String("speed", "", "Set fan speed: auto,1,2,3,4,5")
+	configFile = "./go-pcc.yaml"
+	debug      = false
+	suppress   = false
+	deviceFlag devicesFlag
+	profile    = ""
 )
 
-func readConfig() {
-	viper.SetConfigFile(*configFlag)
-	viper.SetConfigType("yaml")
-	err := viper.ReadInConfig()
-	if err != nil {
-		panic(fmt.Errorf("fatal error config file: %s", err))
-	}
+// allDevices selects every device of the account.
+const allDevices = "all"
+
+// usageError is returned for invalid command line arguments.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
 }
 
 func main() {
 	log.SetHeader(`${time_rfc3339} ${level} -`)
+	os.Exit(run(os.Args[1:]))
+}
+
+// run parses the command line, runs the command and returns the exit code.
+func run(args []string) int {
+	fs := flag.NewFlagSet("go-pcc", flag.ContinueOnError)
+	registerGlobalFlags(fs)
+	legacy := newLegacyFlags(fs)
+	fs.Usage = func() { usage(fs.Output()) }
+	if err := fs.Parse(args); err != nil {
+		return parseExitCode(err)
+	}
+
+	ctx := context.Background()
+	if fs.NArg() == 0 {
+		if !legacyUsed(fs) {
+			fs.Usage()
+			return exitUsage
+		}
+		setupLogging()
+		return report(runLegacy(ctx, legacy))
+	}
+	if legacyUsed(fs) {
+		return report(usageError("deprecated flags cannot be combined with a command, give them after the command instead"))
+	}
+
+	cmd, ok := lookupCommand(fs.Arg(0))
+	if !ok {
+		fmt.Fprintf(fs.Output(), "unknown command %q\n\n", fs.Arg(0))
+		fs.Usage()
+		return exitUsage
+	}
+
+	cfs := flag.NewFlagSet("go-pcc "+cmd.name, flag.ContinueOnError)
+	registerGlobalFlags(cfs)
+	runCommand := cmd.setup(cfs)
+	cfs.Usage = func() { commandUsage(cfs.Output(), cmd, cfs) }
+	if err := cfs.Parse(fs.Args()[1:]); err != nil {
+		return parseExitCode(err)
+	}
 
-	if len(os.Args) < 2 {
-		flag.PrintDefaults()
-		os.Exit(1)
+	if output == outputCSV && !cmd.csv {
+		return report(usageError(fmt.Sprintf("csv output is not available for %s", cmd.name)))
 	}
 
-	flag.Parse()
+	setupLogging()
+	return report(runCommand(ctx, cfs.Args()))
+}
+
+// registerGlobalFlags adds the global flags to a flag set. The current values are used
+// as defaults, so flags given before the command name are kept.
+func registerGlobalFlags(fs *flag.FlagSet) {
+	fs.StringVar(&configFile, "config", configFile, "Path of YAML configuration file")
+	fs.BoolVar(&debug, "debug", debug, "Show debug output")
+	fs.BoolVar(&suppress, "suppress", suppress, "Suppress log messages")
+	fs.Var(&deviceFlag, "device", "Devices to issue command to: name, GUID, GUID prefix, group:<name>, alias or all")
+	fs.Var(&output, "output", "Output format: text,json,yaml,csv")
+	fs.StringVar(&profile, "profile", profile, "Profile of the config file to use")
+}
+
+func setupLogging() {
+	if output != outputText {
+		// keep stdout for the document
+		log.SetOutput(os.Stderr)
+	}
 
 	log.SetLevel(log.INFO)
-	if *suppressFlag {
+	if suppress {
 		log.SetLevel(log.OFF)
 	}
 
-	if *debugFlag {
+	if debug {
 		log.SetLevel(log.DEBUG)
 		log.SetHeader(`${time_rfc3339} ${level} ${short_file} -`)
 		log.Debug("log set to debug level")
 	}
+}
 
-	readConfig()
-	user := viper.GetString("username")
-	pass := viper.GetString("password")
-	token := viper.GetString("token")
+// parseExitCode returns the exit code for a flag parsing error, which the flag package has already reported.
+func parseExitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	return exitUsage
+}
 
-	client := cloudcontrol.NewClient()
+// report prints the error of a command, if any, and returns the matching exit code.
+func report(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	fmt.Fprintf(os.Stderr, "go-pcc: %v\n", err)
+	if errors.As(err, new(usageError)) {
+		fmt.Fprintln(os.Stderr, "run 'go-pcc help' for usage")
+	}
+	return exitCode(err)
+}
 
-	if token == "" {
-		createAndSaveSessionToken(user, pass, &client)
-	} else {
-		if body, err := client.ValidateSession(token); err != nil {
-			log.Infof("invalid session token: %s", string(body))
-			createAndSaveSessionToken(user, pass, &client)
-		} else {
-			log.Debug("session token is valid")
+// exitCode maps an error to the exit code of its class. A command failing for several devices
+// exits with the code shared by all failures, or with exitError if they differ.
+func exitCode(err error) int {
+	failed := &devicesError{}
+	if errors.As(err, &failed) {
+		code := exitCode(failed.errs[0])
+		for _, err := range failed.errs[1:] {
+			if exitCode(err) != code {
+				return exitError
+			}
 		}
+		return code
 	}
 
-	if *listFlag {
-		log.Info("listing available devices")
-		devices, err := client.ListDevices()
-		if err != nil {
-			log.Fatal(err)
-		}
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, new(usageError)):
+		return exitUsage
+	case errors.Is(err, cloudcontrol.ErrUnauthorized), errors.Is(err, cloudcontrol.ErrMissingCredentials):
+		return exitAuth
+	case errors.Is(err, cloudcontrol.ErrDeviceOffline), errors.Is(err, cloudcontrol.ErrNotConfirmed):
+		return exitOffline
+	case errors.Is(err, cloudcontrol.ErrRateLimited):
+		return exitRateLimited
+	case errors.Is(err, cloudcontrol.ErrInvalidCommand), errors.Is(err, cloudcontrol.ErrUnsupported),
+		errors.Is(err, cloudcontrol.ErrDeviceNotFound), errors.Is(err, cloudcontrol.ErrAmbiguousDevice):
+		return exitInvalid
+	case errors.Is(err, cloudcontrol.ErrConflict):
+		return exitConflict
+	}
+	return exitError
+}
 
-		if len(devices) == 0 {
-			log.Fatal("found no devices for configured account")
-		}
+func readConfig() error {
+	viper.SetConfigFile(configFile)
+	viper.SetConfigType("yaml")
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("unable to read config file: %w", err)
+	}
 
-		log.Infof("%d device(s) found:\n", len(devices))
-		for _, device := range devices {
-			fmt.Println(device)
-		}
-		os.Exit(0)
+	if profile == "" {
+		profile = viper.GetString("profile")
+	}
+	// viper keys are case-insensitive, so are the profile names
+	profile = strings.ToLower(profile)
+	if profile != "" && !viper.IsSet("profiles."+profile) {
+		return usageError(fmt.Sprintf("unknown profile %q, expected one of: %s", profile, strings.Join(profiles(), ",")))
 	}
+	return nil
+}
 
-	// read device from configuration file
-	configDevice := viper.GetString("device")
-	if configDevice != "" {
-		log.Debugf("using device %s from config file", configDevice)
-		client.SetDevice(configDevice)
+// profiles returns the names of the profiles in the config file.
+func profiles() []string {
+	var names []string
+	for name := range viper.GetStringMap("profiles") {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
+
+// configKey returns the key of a setting of the selected profile, or the
+// top level key if no profile is selected.
+func configKey(key string) string {
+	return profileKey(profile, key)
+}
 
-	// read device from flag (higher priority)
-	if *deviceFlag != "" {
-		log.Debugf("using device %s from flag", *deviceFlag)
-		client.SetDevice(*deviceFlag)
+// profileKey returns the key of a setting of the named profile, or the
+// top level key if name is empty.
+func profileKey(name string, key string) string {
+	if name == "" {
+		return key
 	}
+	return "profiles." + name + "." + key
+}
 
-	if client.DeviceGUID == "" {
-		log.Fatal("no device configured, use -device flag or set device in config file")
+// newClient creates a client for the named profile, or the top level settings if name is empty,
+// with the cached session token, if any. The configuration must have been read.
+func newClient(name string) (*cloudcontrol.Client, error) {
+	token := viper.GetString(profileKey(name, "token"))
+	store, err := tokenStore(name)
+	if err != nil {
+		return nil, err
 	}
 
-	if *statusFlag {
-		log.Info("fetching device status")
-		status, err := client.GetDeviceStatus()
-		if err != nil {
-			log.Fatal(err)
-		}
+	client := cloudcontrol.NewClient(
+		cloudcontrol.WithCredentials(credentials(name)),
+		cloudcontrol.WithTokenStore(store),
+		cloudcontrol.WithRetryPolicy(cloudcontrol.DefaultRetryPolicy),
+		cloudcontrol.WithRateLimiter(rateLimiter(name)),
+		cloudcontrol.WithValidation(),
+	)
 
-		fmt.Printf("Status: %s\n", types.Operate[status.Parameters.Operate])
-		fmt.Printf("Mode: %s\n", types.ModesReverse[status.Parameters.OperationMode])
-		fmt.Printf("Temperature: %0.1f\n", status.Parameters.TemperatureSet)
-		fmt.Printf("Outside temperature: %0.1f\n", status.Parameters.OutsideTemperature)
-		fmt.Printf("Fan speed: %s\n", types.FanSpeedReverse[status.Parameters.FanSpeed])
-		fmt.Printf("Eco mode: %s\n", types.EcoModeReverse[status.Parameters.EcoMode])
+	if token != "" {
+		// token from config files written by earlier versions, moved to the token store
+		migrateToken(&client, store, token, name)
 	}
 
-	if *historyFlag != "" {
-		log.Infof("fetching historical data for %s\n", *historyFlag)
-		history, err := client.GetDeviceHistory(types.HistoryDataMode[*historyFlag])
-		if err != nil {
-			log.Fatal(err)
-		}
-		fmt.Println("#,AverageSettingTemp,AverageOutsideTemp,Consumption")
-		for _, v := range history.HistoryEntries {
-			fmt.Printf("%v,%v,%v,%v\n", v.DataNumber+1, v.AverageSettingTemp, v.AverageOutsideTemp, v.Consumption)
+	return &client, nil
+}
+
+// migrateToken moves the session token of a profile from the config file to the token store, unless
+// the store already has one, and removes it from the config file once it is stored.
+func migrateToken(client *cloudcontrol.Client, store cloudcontrol.TokenStore, token string, name string) {
+	if client.Token() == "" {
+		log.Debug("migrating session token from config file to token store")
+		client.SetToken(token)
+		if err := store.Save(types.Session{Utoken: token}); err != nil {
+			log.Warnf("unable to save session token: %v", err)
+			return
 		}
 	}
+	if err := removeConfigToken(name); err != nil {
+		log.Warnf("unable to remove the session token from %s, please delete it: %v", configFile, err)
+	}
+}
 
-	if *onFlag {
-		log.Info("turning device on")
-		_, err := client.TurnOn()
-		if err != nil {
-			log.Fatal(err)
-		}
-		fmt.Println("device turned on")
+// removeConfigToken removes the token key of the named profile from the config file,
+// keeping the rest of the file as it is.
+func removeConfigToken(name string) error {
+	info, err := os.Stat(configFile)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	root := yaml.Node{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return err
+	}
+	if len(root.Content) == 0 {
+		return nil
 	}
 
-	if *offFlag {
-		log.Info("turning device off")
-		_, err := client.TurnOff()
-		if err != nil {
-			log.Fatal(err)
+	mapping := root.Content[0]
+	if name != "" {
+		mapping = mappingValue(mappingValue(mapping, "profiles"), name)
+	}
+	if mapping == nil {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, "token") {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			break
 		}
-		fmt.Println("device turned off")
 	}
 
-	if *tempFlag != 0 {
-		log.Infof("setting temperature to %v degrees", *tempFlag)
-		_, err := client.SetTemperature(*tempFlag)
-		if err != nil {
-			log.Fatal(err)
-		}
-		fmt.Printf("temperature set to %v degrees", *tempFlag)
+	buffer := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&root); err != nil {
+		return err
 	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return os.WriteFile(configFile, buffer.Bytes(), info.Mode().Perm())
+}
 
-	if *fanSpeedFlag != "" {
-		log.Infof("setting fan speed to %s", *fanSpeedFlag)
-		_, err := client.SetFanSpeed(types.FanSpeed[*fanSpeedFlag])
-		if err != nil {
-			log.Fatal(err)
+// mappingValue returns the value of a key of a yaml mapping, compared case-insensitively
+// like viper does, or nil if there is none.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if strings.EqualFold(node.Content[i].Value, key) {
+			return node.Content[i+1]
 		}
-		fmt.Printf("fan speed set to %s", *fanSpeedFlag)
 	}
+	return nil
+}
+
+// connect reads the configuration and connects to the account of the selected profile.
+func connect(ctx context.Context) (*cloudcontrol.Client, error) {
+	if err := readConfig(); err != nil {
+		return nil, err
+	}
+	return connectProfile(ctx, profile)
+}
 
-	if *modeFlag != "" {
-		log.Infof("setting mode to %s", *modeFlag)
-		_, err := client.SetMode(types.Modes[*modeFlag])
-		if err != nil {
-			log.Fatal(err)
+// connectProfile creates a client for the named profile with a session token and reverts its
+// expired boosts. The configuration must have been read.
+func connectProfile(ctx context.Context, name string) (*cloudcontrol.Client, error) {
+	client, err := newClient(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.Token() == "" {
+		if err := login(ctx, client, name); err != nil {
+			return nil, err
 		}
-		fmt.Printf("mode set to %s", *modeFlag)
 	}
 
-	if *ecoModeFlag != "" {
-		log.Infof("setting eco mode to %s", *ecoModeFlag)
-		_, err := client.SetEcoMode(types.EcoMode[*ecoModeFlag])
-		if err != nil {
-			log.Fatal(err)
+	if err := revertExpiredBoosts(ctx, client, name); err != nil {
+		log.Warnf("unable to revert expired boosts: %v", err)
+	}
+	return client, nil
+}
+
+// login creates a new session with the credentials of the named profile.
+func login(ctx context.Context, client *cloudcontrol.Client, name string) error {
+	credentials := credentials(name)
+	if credentials.Username == "" || credentials.Password == "" {
+		return fmt.Errorf("%w in config file", cloudcontrol.ErrMissingCredentials)
+	}
+
+	if _, err := client.CreateSessionContext(ctx, credentials.Username, credentials.Password); err != nil {
+		return err
+	}
+
+	log.Debug("new session token created")
+	return nil
+}
+
+// credentials returns the credentials of the named profile, or the top level ones if name is empty.
+func credentials(name string) cloudcontrol.StaticCredentials {
+	return cloudcontrol.StaticCredentials{
+		Username: viper.GetString(profileKey(name, "username")),
+		Password: viper.GetString(profileKey(name, "password")),
+	}
+}
+
+// selectDevices returns the devices selected with -device, or else with the device from the config file.
+// Each selector is a device name, GUID, unique GUID prefix, group:<GroupName>, an alias from the config
+// file or all. Devices selected more than once are returned once.
+func selectDevices(ctx context.Context, client *cloudcontrol.Client) ([]types.Device, error) {
+	// read devices from configuration file
+	var selectors devicesFlag
+	_ = selectors.Set(viper.GetString(configKey("device")))
+	if len(selectors) > 0 {
+		log.Debugf("using device %s from config file", selectors.String())
+	}
+
+	// read devices from flag (higher priority)
+	if len(deviceFlag) > 0 {
+		log.Debugf("using device %s from flag", deviceFlag.String())
+		selectors = deviceFlag
+	}
+
+	if len(selectors) == 0 {
+		return nil, usageError("no device configured, use -device flag or set device in config file")
+	}
+
+	groups, err := client.GetGroupsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// viper keys are case-insensitive, so are the aliases
+	aliases := viper.GetStringMapString(configKey("aliases"))
+
+	var devices []types.Device
+	selected := map[string]bool{}
+	for _, selector := range selectors {
+		if alias, ok := aliases[strings.ToLower(selector)]; ok {
+			log.Debugf("alias %s is %s", selector, alias)
+			selector = alias
+		}
+
+		var matches []types.Device
+		if selector == allDevices {
+			for _, group := range groups.Groups {
+				matches = append(matches, group.Devices...)
+			}
+		} else if matches, err = cloudcontrol.MatchDevices(groups, selector); err != nil {
+			return nil, err
 		}
-		fmt.Printf("eco mode set to %s", *ecoModeFlag)
+
+		for _, device := range matches {
+			if !selected[device.DeviceGUID] {
+				selected[device.DeviceGUID] = true
+				devices = append(devices, device)
+			}
+		}
+	}
+
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("%w: no devices in account", cloudcontrol.ErrDeviceNotFound)
 	}
+	return devices, nil
 }
 
-func createAndSaveSessionToken(user string, pass string, client *cloudcontrol.Client) {
-	if user == "" || pass == "" {
-		log.Fatal("missing username and/or password in config file")
+// selectDevice returns the single device selected, for commands not taking several devices.
+func selectDevice(ctx context.Context, client *cloudcontrol.Client) (*cloudcontrol.Device, error) {
+	devices, err := selectDevices(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) > 1 {
+		return nil, usageError(fmt.Sprintf("%d devices selected, this command works on a single device", len(devices)))
 	}
+	return client.Device(devices[0].DeviceGUID), nil
+}
 
-	_, err := client.CreateSession(user, pass)
+// tokenStore returns the store used to cache the session token of a profile between runs.
+// The token is encrypted when GO_PCC_TOKEN_PASSPHRASE is set.
+func tokenStore(name string) (cloudcontrol.TokenStore, error) {
+	path, err := tokenPath(name)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	viper.Set("token", client.Utoken)
-	err = viper.WriteConfig()
+	if passphrase := os.Getenv("GO_PCC_TOKEN_PASSPHRASE"); passphrase != "" {
+		return cloudcontrol.NewEncryptedFileTokenStore(path, passphrase), nil
+	}
+	return cloudcontrol.NewFileTokenStore(path), nil
+}
+
+// tokenPath returns the path of the session token cache of a profile.
+func tokenPath(name string) (string, error) {
+	if path := viper.GetString(profileKey(name, "tokenfile")); path != "" {
+		return path, nil
+	}
+	dir, err := cacheDir()
 	if err != nil {
-		log.Fatal("unable to write session token to config file")
+		return "", err
 	}
+	return filepath.Join(dir, profileFile(name, "token")), nil
+}
+
+// rateLimiter returns a rate limiter for a profile, shared by all go-pcc processes of the current user.
+func rateLimiter(name string) *cloudcontrol.RateLimiter {
+	var opts []cloudcontrol.RateLimiterOption
+	if dir, err := cacheDir(); err == nil {
+		opts = append(opts, cloudcontrol.SharedAcrossProcesses(filepath.Join(dir, profileFile(name, "ratelimit"))))
+	} else {
+		log.Warnf("rate limit not shared with other processes: %v", err)
+	}
+	return cloudcontrol.NewRateLimiter(cloudcontrol.DefaultRateLimits, opts...)
+}
+
+// boostStore returns the store of the boosts of a profile waiting to be reverted.
+func boostStore(name string) (*cloudcontrol.BoostStore, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return cloudcontrol.NewBoostStore(filepath.Join(dir, profileFile(name, "boosts"))), nil
+}
 
-	log.Debugf("new session token created and written to config file: %s", client.Utoken)
+// profileFile returns the name of a file in the cache directory, suffixed with the named profile
+// so that each account has its own session token, rate limit and pending boosts.
+func profileFile(name string, file string) string {
+	if name == "" {
+		return file
+	}
+	return file + "-" + name
+}
+
+// cacheDir returns the directory holding the token cache, rate limiter state and pending boosts.
+func cacheDir() (string, error) {
+	path, err := cloudcontrol.DefaultTokenPath()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine cache location: %w", err)
+	}
+	return filepath.Dir(path), nil
 }
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	cloudcontrol "github.com/jesper-nord/go-pcc/client"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// quiet discards what the command line parsing writes to stdout and stderr during the test.
+func quiet(t *testing.T) {
+	stdout, stderr := os.Stdout, os.Stderr
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout, os.Stderr = devNull, devNull
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		devNull.Close()
+	})
+}
+
+func TestRun_UsageErrors(t *testing.T) {
+	quiet(t)
+
+	assert.Equal(t, exitUsage, run(nil))
+	assert.Equal(t, exitUsage, run([]string{"bogus"}))
+	assert.Equal(t, exitUsage, run([]string{"set", "-mode", "hat"}))
+	assert.Equal(t, exitUsage, run([]string{"set", "-temp", "warm"}))
+	assert.Equal(t, exitUsage, run([]string{"history", "decade"}))
+	assert.Equal(t, exitUsage, run([]string{"status", "extra"}))
+	assert.Equal(t, exitUsage, run([]string{"-on", "status"}))
+	assert.Equal(t, exitOK, run([]string{"help", "set"}))
+	assert.Equal(t, exitOK, run([]string{"set", "-h"}))
+}
+
+func newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+func TestSettings_TemperatureZero(t *testing.T) {
+	fs := newFlagSet()
+	settings := newSettings(fs, "")
+	assert.NoError(t, fs.Parse([]string{"-temp", "0"}))
+
+	client := cloudcontrol.NewClient()
+	command := client.Device("living").Control()
+	changes, err := settings.apply(command)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"temperature set to 0 degrees"}, changes)
+	assert.Equal(t, 0.0, *command.Parameters().TemperatureSet)
+}
+
+func TestSettings_OnAndOff(t *testing.T) {
+	fs := newFlagSet()
+	settings := newSettings(fs, "")
+	assert.NoError(t, fs.Parse([]string{"-on", "-off"}))
+
+	client := cloudcontrol.NewClient()
+	_, err := settings.apply(client.Device("living").Control())
+
+	assert.Equal(t, exitUsage, exitCode(err))
+}
+
+func TestSettings_Unavailable(t *testing.T) {
+	fs := newFlagSet()
+	settings := newSettings(fs, "")
+
+	assert.NoError(t, fs.Parse([]string{"-nanoe", "unavailable"}))
+	client := cloudcontrol.NewClient()
+	command := client.Device("living").Control()
+	_, err := settings.apply(command)
+	assert.NoError(t, err)
+	_, err = command.Send(context.Background())
+	assert.Equal(t, exitInvalid, exitCode(err))
+	assert.EqualError(t, err, "invalid nanoe unavailable: cannot be set")
+
+	assert.NoError(t, fs.Parse([]string{"-nanoe", "mode-g", "-iauto", "off"}))
+	assert.Equal(t, types.NanoeModeG, settings.nanoe.value)
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, exitOK, exitCode(nil))
+	assert.Equal(t, exitError, exitCode(errors.New("boom")))
+	assert.Equal(t, exitAuth, exitCode(fmt.Errorf("login: %w", cloudcontrol.ErrMissingCredentials)))
+	assert.Equal(t, exitAuth, exitCode(&cloudcontrol.APIError{StatusCode: 401}))
+	assert.Equal(t, exitOffline, exitCode(cloudcontrol.ErrNotConfirmed))
+	assert.Equal(t, exitRateLimited, exitCode(&cloudcontrol.APIError{StatusCode: 429}))
+	assert.Equal(t, exitInvalid, exitCode(&cloudcontrol.ValidationError{Field: "temperatureSet"}))
+	assert.Equal(t, exitInvalid, exitCode(&cloudcontrol.AmbiguousDeviceError{Selector: "office"}))
+	assert.Equal(t, exitConflict, exitCode(&cloudcontrol.ConflictError{Field: "operate"}))
+}
+
+func TestExitCode_SeveralDevices(t *testing.T) {
+	offline := fmt.Errorf("CZ-1: %w", cloudcontrol.ErrDeviceOffline)
+
+	assert.Equal(t, exitOffline, exitCode(&devicesError{total: 3, errs: []error{offline, offline}}))
+	assert.Equal(t, exitError, exitCode(&devicesError{total: 3, errs: []error{offline, cloudcontrol.ErrConflict}}))
+}
+
+func TestDevicesFlag(t *testing.T) {
+	fs := newFlagSet()
+	var devices devicesFlag
+	fs.Var(&devices, "device", "")
+
+	assert.NoError(t, fs.Parse([]string{"-device", "living room, bedroom", "-device", "group:Upstairs"}))
+	assert.Equal(t, devicesFlag{"living room", "bedroom", "group:Upstairs"}, devices)
+}
+
+func TestReadConfig_Profiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "go-pcc.yaml")
+	config := "username: me@example.com\nprofiles:\n  home:\n    username: home@example.com\n" +
+		"  Cabin:\n    username: cabin@example.com\n    device: kitchen\n"
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defaultConfigFile := configFile
+	t.Cleanup(func() { configFile, profile = defaultConfigFile, "" })
+
+	configFile, profile = path, "cabin"
+	assert.NoError(t, readConfig())
+	assert.Equal(t, "cabin@example.com", credentials(profile).Username)
+	assert.Equal(t, "me@example.com", credentials("").Username)
+	assert.Equal(t, []string{"cabin", "home"}, profiles())
+	assert.Equal(t, "token-cabin", profileFile(profile, "token"))
+
+	profile = "attic"
+	assert.Equal(t, exitUsage, exitCode(readConfig()))
+
+	profile = ""
+	assert.NoError(t, readConfig())
+	assert.Equal(t, "me@example.com", credentials(profile).Username)
+	assert.Equal(t, "token", profileFile(profile, "token"))
+}
+
+func TestNewClient_MigratesToken(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("GO_PCC_TOKEN_PASSPHRASE", "")
+	path := filepath.Join(dir, "go-pcc.yaml")
+	config := "# credentials\nusername: me@example.com\ntoken: top-token\nprofiles:\n  Cabin:\n" +
+		"    username: cabin@example.com\n    token: cabin-token\n    tokenfile: " + filepath.Join(dir, "cabin") + "\n"
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defaultConfigFile := configFile
+	t.Cleanup(func() { configFile, profile = defaultConfigFile, "" })
+
+	configFile, profile = path, "cabin"
+	assert.NoError(t, readConfig())
+	client, err := newClient(profile)
+	assert.NoError(t, err)
+	assert.Equal(t, "cabin-token", client.Token())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "# credentials\nusername: me@example.com\ntoken: top-token\nprofiles:\n  Cabin:\n"+
+		"    username: cabin@example.com\n    tokenfile: "+filepath.Join(dir, "cabin")+"\n", string(data))
+	session, err := cloudcontrol.NewFileTokenStore(filepath.Join(dir, "cabin")).Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "cabin-token", session.Utoken)
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/jesper-nord/go-pcc/types"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// schemaVersion is the version of the json and yaml documents, raised on incompatible changes.
+const schemaVersion = 1
+
+// outputFormat is the value of the -output flag.
+type outputFormat string
+
+const (
+	outputText outputFormat = "text"
+	outputJSON outputFormat = "json"
+	outputYAML outputFormat = "yaml"
+	outputCSV  outputFormat = "csv"
+)
+
+func (f *outputFormat) String() string {
+	if f == nil {
+		return ""
+	}
+	return string(*f)
+}
+
+func (f *outputFormat) Set(s string) error {
+	switch format := outputFormat(strings.ToLower(s)); format {
+	case outputText, outputJSON, outputYAML, outputCSV:
+		*f = format
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, expected one of: text,json,yaml,csv", s)
+}
+
+var (
+	output           = outputText
+	stdout io.Writer = os.Stdout
+)
+
+// document is the envelope of the json and yaml output. Its data is a selection of the API
+// responses with fixed names, documented in the README, so that the schema does not follow API changes.
+type document struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Kind          string `json:"kind"`
+	Data          any    `json:"data"`
+}
+
+// table is implemented by output that can be written as csv.
+type table interface {
+	header() []string
+	rows() [][]string
+}
+
+// render writes data in the selected output format, or calls text for text output.
+// The yaml output is the json document in yaml syntax, so both share one schema.
+func render(kind string, data any, text func(w io.Writer)) error {
+	switch output {
+	case outputJSON:
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(document{SchemaVersion: schemaVersion, Kind: kind, Data: data})
+	case outputYAML:
+		return writeYAML(stdout, document{SchemaVersion: schemaVersion, Kind: kind, Data: data})
+	case outputCSV:
+		t, ok := data.(table)
+		if !ok {
+			return usageError(fmt.Sprintf("csv output is not available for %s", kind))
+		}
+		writer := csv.NewWriter(stdout)
+		_ = writer.Write(t.header())
+		return writer.WriteAll(t.rows())
+	}
+	text(stdout)
+	return nil
+}
+
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	node := yaml.Node{}
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	buffer := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(buffer.Bytes())
+	return err
+}
+
+// blockStyle drops the json flow style from the mappings and sequences of a node tree, so it is
+// written as block yaml. String values stay quoted, so that yaml 1.1 parsers do not read "on" as a boolean.
+func blockStyle(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		node.Style = 0
+		for i := 0; i < len(node.Content); i += 2 {
+			node.Content[i].Style = 0
+		}
+	case yaml.SequenceNode:
+		node.Style = 0
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func formatMeasurement(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
+
+// deviceList is the list of devices of the account.
+type deviceList []types.DeviceSummary
+
+func (l deviceList) header() []string {
+	return []string{"guid", "name", "group", "model", "type", "online", "power", "mode", "temperatureSet",
+		"insideTemperature", "outsideTemperature"}
+}
+
+func (l deviceList) rows() [][]string {
+	var rows [][]string
+	for _, d := range l {
+		rows = append(rows, []string{d.GUID, d.Name, d.Group, d.Model, d.Type, strconv.FormatBool(d.Online),
+			d.Power.String(), d.Mode.String(), strconv.FormatFloat(d.TemperatureSet, 'f', -1, 64),
+			formatMeasurement(d.InsideTemperature), formatMeasurement(d.OutsideTemperature)})
+	}
+	return rows
+}
+
+// profileDevice is a device listed across all profiles.
+type profileDevice struct {
+	Profile string `json:"profile"`
+	types.DeviceSummary
+}
+
+// profileDeviceList is the list of devices of all profiles.
+type profileDeviceList []profileDevice
+
+func (l profileDeviceList) header() []string {
+	return append([]string{"profile"}, deviceList{}.header()...)
+}
+
+func (l profileDeviceList) rows() [][]string {
+	var rows [][]string
+	for _, d := range l {
+		rows = append(rows, append([]string{d.Profile}, deviceList{d.DeviceSummary}.rows()[0]...))
+	}
+	return rows
+}
+
+// writeDeviceTable writes the devices as an aligned table.
+func writeDeviceTable(w io.Writer, summaries []types.DeviceSummary) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "GROUP\tNAME\tGUID\tMODEL\tONLINE\tPOWER\tMODE\tSET\tINSIDE\tOUTSIDE")
+	for _, d := range summaries {
+		online := "yes"
+		if !d.Online {
+			online = "no"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%0.1f\t%s\t%s\n", d.Group, d.Name, d.GUID, d.Model,
+			online, d.Power, d.Mode, d.TemperatureSet, formatTemperature(d.InsideTemperature),
+			formatTemperature(d.OutsideTemperature))
+	}
+	_ = table.Flush()
+}
+
+// formatTemperature formats a temperature for text output, "-" if it is not measured.
+func formatTemperature(temperature *float64) string {
+	if temperature == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%0.1f", *temperature)
+}
+
+// statusOutput is the current state of a device.
+type statusOutput struct {
+	GUID               string                `json:"guid"`
+	Online             bool                  `json:"online"`
+	Power              types.PowerState      `json:"power"`
+	Mode               types.OperationMode   `json:"mode"`
+	TemperatureSet     float64               `json:"temperatureSet"`
+	InsideTemperature  *float64              `json:"insideTemperature"`
+	OutsideTemperature *float64              `json:"outsideTemperature"`
+	FanSpeed           types.FanSpeed        `json:"fanSpeed"`
+	EcoMode            types.EcoMode         `json:"ecoMode"`
+	VerticalSwing      types.VerticalSwing   `json:"verticalSwing"`
+	HorizontalSwing    types.HorizontalSwing `json:"horizontalSwing"`
+	Nanoe              types.NanoeMode       `json:"nanoe"`
+	EcoNavi            types.FeatureState    `json:"ecoNavi"`
+	IAutoX             types.FeatureState    `json:"iAutoX"`
+}
+
+func newStatusOutput(guid string, status types.Device) statusOutput {
+	p := status.Parameters
+	return statusOutput{
+		GUID:               guid,
+		Online:             p.Reachable(),
+		Power:              p.Power(),
+		Mode:               p.Mode(),
+		TemperatureSet:     p.TemperatureSet,
+		InsideTemperature:  types.Measurement(p.InsideTemperature, types.TemperatureUnavailable),
+		OutsideTemperature: types.Measurement(p.OutsideTemperature, types.TemperatureUnavailable),
+		FanSpeed:           p.Fan(),
+		EcoMode:            p.Eco(),
+		VerticalSwing:      p.VerticalSwing(),
+		HorizontalSwing:    p.HorizontalSwing(),
+		Nanoe:              p.NanoeMode(),
+		EcoNavi:            p.EcoNaviState(),
+		IAutoX:             p.IAutoState(),
+	}
+}
+
+func (s statusOutput) header() []string {
+	return []string{"guid", "online", "power", "mode", "temperatureSet", "insideTemperature", "outsideTemperature",
+		"fanSpeed", "ecoMode", "verticalSwing", "horizontalSwing", "nanoe", "ecoNavi", "iAutoX"}
+}
+
+func (s statusOutput) rows() [][]string {
+	return [][]string{{s.GUID, strconv.FormatBool(s.Online), s.Power.String(), s.Mode.String(),
+		strconv.FormatFloat(s.TemperatureSet, 'f', -1, 64), formatMeasurement(s.InsideTemperature),
+		formatMeasurement(s.OutsideTemperature), s.FanSpeed.String(), s.EcoMode.String(), s.VerticalSwing.String(),
+		s.HorizontalSwing.String(), s.Nanoe.String(), s.EcoNavi.String(), s.IAutoX.String()}}
+}
+
+// historyOutput is the history of a device, entries without data have null values.
+type historyOutput struct {
+	GUID              string               `json:"guid"`
+	Period            types.HistoryPeriod  `json:"period"`
+	EnergyConsumption float64              `json:"energyConsumption"`
+	EstimatedCost     float64              `json:"estimatedCost"`
+	CurrencyUnit      string               `json:"currencyUnit"`
+	Entries           []historyEntryOutput `json:"entries"`
+}
+
+type historyEntryOutput struct {
+	Number             int64    `json:"number"`
+	Consumption        *float64 `json:"consumption"`
+	Cost               *float64 `json:"cost"`
+	AverageSettingTemp *float64 `json:"averageSettingTemp"`
+	AverageInsideTemp  *float64 `json:"averageInsideTemp"`
+	AverageOutsideTemp *float64 `json:"averageOutsideTemp"`
+}
+
+func newHistoryOutput(guid string, period types.HistoryPeriod, history types.History) historyOutput {
+	h := historyOutput{
+		GUID:              guid,
+		Period:            period,
+		EnergyConsumption: history.EnergyConsumption,
+		EstimatedCost:     history.EstimatedCost,
+		CurrencyUnit:      history.CurrencyUnit,
+		Entries:           []historyEntryOutput{},
+	}
+	for _, e := range history.HistoryEntries {
+		h.Entries = append(h.Entries, historyEntryOutput{
+			Number:             e.DataNumber + 1,
+			Consumption:        types.Measurement(e.Consumption, types.HistoryUnavailable),
+			Cost:               types.Measurement(e.Cost, types.HistoryUnavailable),
+			AverageSettingTemp: types.Measurement(e.AverageSettingTemp, types.HistoryUnavailable),
+			AverageInsideTemp:  types.Measurement(e.AverageInsideTemp, types.HistoryUnavailable),
+			AverageOutsideTemp: types.Measurement(e.AverageOutsideTemp, types.HistoryUnavailable),
+		})
+	}
+	return h
+}
+
+func (h historyOutput) header() []string {
+	return []string{"number", "consumption", "cost", "averageSettingTemp", "averageInsideTemp", "averageOutsideTemp"}
+}
+
+func (h historyOutput) rows() [][]string {
+	var rows [][]string
+	for _, e := range h.Entries {
+		rows = append(rows, []string{strconv.FormatInt(e.Number, 10), formatMeasurement(e.Consumption),
+			formatMeasurement(e.Cost), formatMeasurement(e.AverageSettingTemp), formatMeasurement(e.AverageInsideTemp),
+			formatMeasurement(e.AverageOutsideTemp)})
+	}
+	return rows
+}
+
+// changeOutput is the result of a command changing a device.
+type changeOutput struct {
+	GUID    string   `json:"guid"`
+	Changes []string `json:"changes"`
+	Applied []string `json:"applied,omitempty"`
+}
+
+// deviceResult is the outcome of a command for one of several devices.
+type deviceResult struct {
+	GUID   string `json:"guid"`
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+	Result any    `json:"result,omitempty"`
+}
+
+// resultList is the outcome of a command run for one or several devices.
+type resultList []deviceResult
+
+func (l resultList) header() []string {
+	return []string{"guid", "name", "ok", "error"}
+}
+
+func (l resultList) rows() [][]string {
+	var rows [][]string
+	for _, r := range l {
+		rows = append(rows, []string{r.GUID, r.Name, strconv.FormatBool(r.OK), r.Error})
+	}
+	return rows
+}
+
+// resultTable is a resultList whose csv output has the columns of the device results,
+// after the summary of each device. The json and yaml output is the list itself.
+type resultTable struct {
+	results resultList
+	// columns is the zero value of the results, nil if they have no columns.
+	columns table
+}
+
+func (t resultTable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.results)
+}
+
+func (t resultTable) header() []string {
+	header := t.results.header()
+	for _, i := range t.resultColumns() {
+		header = append(header, t.columns.header()[i])
+	}
+	return header
+}
+
+func (t resultTable) rows() [][]string {
+	rows := t.results.rows()
+	indexes := t.resultColumns()
+	for i, r := range t.results {
+		var values []string
+		if result, ok := r.Result.(table); ok {
+			values = result.rows()[0]
+		}
+		for _, index := range indexes {
+			value := ""
+			if values != nil {
+				value = values[index]
+			}
+			rows[i] = append(rows[i], value)
+		}
+	}
+	return rows
+}
+
+// resultColumns returns the indexes of the result columns not already in the summary of a device.
+func (t resultTable) resultColumns() []int {
+	if t.columns == nil {
+		return nil
+	}
+	summary := map[string]bool{}
+	for _, column := range t.results.header() {
+		summary[column] = true
+	}
+	var indexes []int
+	for i, column := range t.columns.header() {
+		if !summary[column] {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// writeResultTable writes the outcome per device as an aligned table.
+func writeResultTable(w io.Writer, results resultList) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "NAME\tGUID\tRESULT")
+	for _, r := range results {
+		result := "ok"
+		if !r.OK {
+			result = r.Error
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", r.Name, r.GUID, result)
+	}
+	_ = table.Flush()
+}
+
+// configOutput is the configuration in use, without secrets.
+type configOutput struct {
+	ConfigFile     string            `json:"configFile"`
+	Profile        string            `json:"profile,omitempty"`
+	Profiles       []string          `json:"profiles,omitempty"`
+	Username       string            `json:"username"`
+	PasswordSet    bool              `json:"passwordSet"`
+	Device         string            `json:"device"`
+	Aliases        map[string]string `json:"aliases"`
+	TokenFile      string            `json:"tokenFile"`
+	TokenEncrypted bool              `json:"tokenEncrypted"`
+}
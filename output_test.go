@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// renderTo renders data in the given format and returns what was written.
+func renderTo(t *testing.T, format outputFormat, kind string, data any) string {
+	buffer := &bytes.Buffer{}
+	previousOutput, previousStdout := output, stdout
+	output, stdout = format, buffer
+	t.Cleanup(func() { output, stdout = previousOutput, previousStdout })
+
+	if err := render(kind, data, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.String()
+}
+
+func statusFixture() statusOutput {
+	return newStatusOutput("living", types.Device{Parameters: types.DeviceParameters{
+		Online:             true,
+		Operate:            1,
+		OperationMode:      int64(types.ModeHeat),
+		TemperatureSet:     21.5,
+		InsideTemperature:  126,
+		OutsideTemperature: 4,
+		FanSpeed:           int64(types.FanSpeedMid),
+		FanAutoMode:        int64(types.AutoSwingBoth),
+	}})
+}
+
+func TestRender_JSON(t *testing.T) {
+	out := renderTo(t, outputJSON, "status", statusFixture())
+
+	document := map[string]any{}
+	assert.NoError(t, json.Unmarshal([]byte(out), &document))
+	assert.Equal(t, float64(schemaVersion), document["schemaVersion"])
+	assert.Equal(t, "status", document["kind"])
+	data := document["data"].(map[string]any)
+	assert.Equal(t, "on", data["power"])
+	assert.Equal(t, "heat", data["mode"])
+	assert.Equal(t, "3", data["fanSpeed"])
+	assert.Equal(t, "auto", data["verticalSwing"])
+	assert.Nil(t, data["insideTemperature"])
+	assert.Equal(t, 4.0, data["outsideTemperature"])
+}
+
+func TestRender_YAML(t *testing.T) {
+	out := renderTo(t, outputYAML, "status", statusFixture())
+
+	assert.Contains(t, out, "schemaVersion: 1\nkind: \"status\"\ndata:\n  guid: \"living\"\n  online: true\n  power: \"on\"\n  mode: \"heat\"\n")
+	assert.Contains(t, out, "  insideTemperature: null\n")
+}
+
+func TestRender_CSV(t *testing.T) {
+	history := newHistoryOutput("living", types.HistoryDay, types.History{HistoryEntries: []types.HistoryEntry{
+		{DataNumber: 0, Consumption: 0.5, Cost: 0.1, AverageSettingTemp: 19, AverageInsideTemp: 18.75, AverageOutsideTemp: 11.25},
+		{DataNumber: 1, Consumption: -255, Cost: -255, AverageSettingTemp: -255, AverageInsideTemp: -255, AverageOutsideTemp: -255},
+	}})
+
+	out := renderTo(t, outputCSV, "history", history)
+
+	assert.Equal(t, "number,consumption,cost,averageSettingTemp,averageInsideTemp,averageOutsideTemp\n"+
+		"1,0.5,0.1,19,18.75,11.25\n"+
+		"2,,,,,\n", out)
+}
+
+func TestRender_CSVNotAvailable(t *testing.T) {
+	previousOutput := output
+	output = outputCSV
+	defer func() { output = previousOutput }()
+
+	err := render("change", changeOutput{GUID: "living"}, nil)
+
+	assert.Equal(t, exitUsage, exitCode(err))
+}
+
+func TestWriteDeviceTable(t *testing.T) {
+	inside := 22.5
+	buffer := &bytes.Buffer{}
+
+	writeDeviceTable(buffer, []types.DeviceSummary{
+		{GUID: "CZ-1", Name: "Living room", Group: "Home", Model: "S-125", Online: true, Power: types.PowerOn,
+			Mode: types.ModeHeat, TemperatureSet: 21, InsideTemperature: &inside},
+		{GUID: "CZ-2", Name: "Bedroom", Group: "Home", Model: "S-71", Mode: types.ModeCool, TemperatureSet: 24.5},
+	})
+
+	assert.Equal(t, ""+
+		"GROUP  NAME         GUID  MODEL  ONLINE  POWER  MODE  SET   INSIDE  OUTSIDE\n"+
+		"Home   Living room  CZ-1  S-125  yes     on     heat  21.0  22.5    -\n"+
+		"Home   Bedroom      CZ-2  S-71   no      off    cool  24.5  -       -\n", buffer.String())
+}
+
+func TestWriteResultTable(t *testing.T) {
+	buffer := &bytes.Buffer{}
+
+	writeResultTable(buffer, resultList{
+		{GUID: "CZ-1", Name: "Living room", OK: true},
+		{GUID: "CZ-2", Name: "Bedroom", Error: "device offline"},
+	})
+
+	assert.Equal(t, ""+
+		"NAME         GUID  RESULT\n"+
+		"Living room  CZ-1  ok\n"+
+		"Bedroom      CZ-2  device offline\n", buffer.String())
+}
+
+func TestRender_CSVProfileDevices(t *testing.T) {
+	devices := profileDeviceList{
+		{Profile: "cabin", DeviceSummary: types.DeviceSummary{GUID: "CZ-1", Name: "Kitchen", Group: "Cabin",
+			Power: types.PowerOff, Mode: types.ModeHeat, TemperatureSet: 16}},
+	}
+
+	out := renderTo(t, outputCSV, "devices", devices)
+
+	assert.Equal(t, ""+
+		"profile,guid,name,group,model,type,online,power,mode,temperatureSet,insideTemperature,outsideTemperature\n"+
+		"cabin,CZ-1,Kitchen,Cabin,,,false,off,heat,16,,\n", out)
+}
+
+func TestWriteChanges_Confirmed(t *testing.T) {
+	buffer := &bytes.Buffer{}
+
+	writeChanges(buffer, changeOutput{GUID: "CZ-1", Changes: []string{"power on", "temperature 21.5"},
+		Applied: []string{"operate", "temperatureSet"}})
+
+	assert.Equal(t, "power on\ntemperature 21.5\nconfirmed: operate, temperatureSet\n", buffer.String())
+}
+
+func TestRender_CSVStatusResults(t *testing.T) {
+	results := resultTable{results: resultList{
+		{GUID: "living", Name: "Living room", OK: true, Result: statusFixture()},
+		{GUID: "CZ-2", Name: "Bedroom", Error: "device offline"},
+	}, columns: statusOutput{}}
+
+	out := renderTo(t, outputCSV, "status", results)
+
+	assert.Equal(t, ""+
+		"guid,name,ok,error,online,power,mode,temperatureSet,insideTemperature,outsideTemperature,fanSpeed,ecoMode,verticalSwing,horizontalSwing,nanoe,ecoNavi,iAutoX\n"+
+		"living,Living room,true,,true,on,heat,21.5,,4,3,auto,auto,auto,unavailable,unavailable,unavailable\n"+
+		"CZ-2,Bedroom,false,device offline,,,,,,,,,,,,,\n", out)
+
+	var document map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(renderTo(t, outputJSON, "status", results)), &document))
+	assert.Len(t, document["data"], 2)
+}
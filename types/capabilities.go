@@ -0,0 +1,125 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Flag is a capability flag. The API sends these as booleans or as 0/1,
+// so both forms are accepted when unmarshalling
+type Flag bool
+
+// UnmarshalJSON accepts true, false, numbers (non-zero is true) and null
+func (f *Flag) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	switch string(data) {
+	case "true":
+		*f = true
+		return nil
+	case "false", "null", "":
+		*f = false
+		return nil
+	}
+	n, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("invalid flag value %s", data)
+	}
+	*f = n != 0
+	return nil
+}
+
+// TemperatureRange is the set-point range supported in a mode, in whole degrees
+type TemperatureRange struct {
+	Min int64 `json:"min"`
+	Max int64 `json:"max"`
+}
+
+// Known reports whether the device reported a range
+func (r TemperatureRange) Known() bool {
+	return r.Max != 0
+}
+
+// Contains reports whether the temperature is within the range. Unknown ranges contain everything
+func (r TemperatureRange) Contains(temperature float64) bool {
+	return !r.Known() || (temperature >= float64(r.Min) && temperature <= float64(r.Max))
+}
+
+// TemperatureStep is the resolution of the temperature set-point
+const TemperatureStep = 0.5
+
+// ValidTemperatureStep reports whether the temperature is a multiple of TemperatureStep
+func ValidTemperatureStep(temperature float64) bool {
+	return math.Mod(temperature/TemperatureStep, 1) == 0
+}
+
+// ModeCapability describes the support of a device for one AC mode
+type ModeCapability struct {
+	Available   bool             `json:"available"`
+	Temperature TemperatureRange `json:"temperature"`
+}
+
+// Capabilities is the model of what a device supports, derived from the flat Device fields
+// and the nested modeAvlList
+type Capabilities struct {
+	Auto ModeCapability `json:"auto"`
+	Dry  ModeCapability `json:"dry"`
+	Cool ModeCapability `json:"cool"`
+	Heat ModeCapability `json:"heat"`
+	Fan  ModeCapability `json:"fan"`
+
+	FanSpeeds     int64 `json:"fanSpeeds"`
+	FanDirections int64 `json:"fanDirections"`
+
+	QuietMode    bool `json:"quietMode"`
+	PowerfulMode bool `json:"powerfulMode"`
+	Nanoe        bool `json:"nanoe"`
+	EcoNavi      bool `json:"ecoNavi"`
+	IAutoX       bool `json:"iAutoX"`
+	AirSwingLR   bool `json:"airSwingLR"`
+
+	// ModeAvailability is the modeAvlList as reported, already taken into account for Auto and Fan
+	ModeAvailability ModeAvailability `json:"modeAvailability"`
+}
+
+// Capabilities returns the capability model of the device. Auto and fan mode are
+// available if either the top level flag or the modeAvlList flag is set
+func (d Device) Capabilities() Capabilities {
+	return Capabilities{
+		Auto: ModeCapability{bool(d.AutoMode || d.ModeAvailability.AutoMode), TemperatureRange{d.AutoTempMin, d.AutoTempMax}},
+		Dry:  ModeCapability{bool(d.DryMode), TemperatureRange{d.DryTempMin, d.DryTempMax}},
+		Cool: ModeCapability{bool(d.CoolMode), TemperatureRange{d.CoolTempMin, d.CoolTempMax}},
+		Heat: ModeCapability{bool(d.HeatMode), TemperatureRange{d.HeatTempMin, d.HeatTempMax}},
+		Fan:  ModeCapability{Available: bool(d.FanMode || d.ModeAvailability.FanMode)},
+
+		FanSpeeds:     d.FanSpeedMode,
+		FanDirections: d.FanDirectionMode,
+
+		QuietMode:    bool(d.QuietMode),
+		PowerfulMode: bool(d.PowerfulMode),
+		Nanoe:        bool(d.Nanoe),
+		EcoNavi:      bool(d.EcoNavi),
+		IAutoX:       bool(d.IautoX),
+		AirSwingLR:   bool(d.AirSwingLR),
+
+		ModeAvailability: d.ModeAvailability,
+	}
+}
+
+// Mode returns the capability for an operation mode, and false for unknown modes
+func (c Capabilities) Mode(mode OperationMode) (ModeCapability, bool) {
+	switch mode {
+	case ModeAuto:
+		return c.Auto, true
+	case ModeDry:
+		return c.Dry, true
+	case ModeCool:
+		return c.Cool, true
+	case ModeHeat:
+		return c.Heat, true
+	case ModeFan:
+		return c.Fan, true
+	}
+	return ModeCapability{}, false
+}
@@ -9,4 +9,12 @@ const (
 	UrlPathControl      = "/deviceStatus/control"
 	UrlPathValidate     = "/auth/agreement/status/1"
 	SuccessResponse     = `{"result":0}`
+	DefaultUserAgent    = "G-RAC"
+	DefaultAppVersion   = "1.20.0"
+
+	ResultCodeTokenExpired  = 4100
+	ResultCodeDeviceOffline = 5005
+
+	TemperatureUnavailable = 126  // reported for temperatures the unit does not measure
+	HistoryUnavailable     = -255 // reported for history entries without data
 )
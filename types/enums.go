@@ -0,0 +1,381 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The enum types of this package share these helpers. They all marshal to their
+// name in text, JSON and YAML, and accept either the name or the API number when
+// unmarshalling. Values without a name, such as new values reported by the API, are
+// marshalled as their plain number and read back as is, while the Parse functions
+// for user input reject them. The API structs DeviceParameters and DeviceControlParameters
+// keep plain numbers since that is what Panasonic expects on the wire.
+
+func enumString[T ~int64](names map[T]string, value T, typeName string) string {
+	if name, ok := names[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("%s(%d)", typeName, int64(value))
+}
+
+// enumText returns the name of an enum value, or its number if it has no name.
+func enumText[T ~int64](names map[T]string, value T) string {
+	if name, ok := names[value]; ok {
+		return name
+	}
+	return strconv.FormatInt(int64(value), 10)
+}
+
+// marshalEnumJSON marshals an enum value to its name, or to a JSON number if it has no name.
+func marshalEnumJSON[T ~int64](names map[T]string, value T) ([]byte, error) {
+	if name, ok := names[value]; ok {
+		return json.Marshal(name)
+	}
+	return json.Marshal(int64(value))
+}
+
+// unmarshalEnum parses a name or number with parse, also accepting the number of a value without a name.
+func unmarshalEnum[T ~int64](parse func(string) (T, error), s string) (T, error) {
+	value, err := parse(s)
+	if err != nil {
+		if n, nerr := strconv.ParseInt(strings.TrimSpace(s), 10, 64); nerr == nil {
+			return T(n), nil
+		}
+	}
+	return value, err
+}
+
+// parseEnum parses the name of an enum value, or its number, rejecting unknown values.
+func parseEnum[T ~int64](names map[T]string, s string, what string) (T, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for value, name := range names {
+		if name == s {
+			return value, nil
+		}
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if _, ok := names[T(n)]; ok {
+			return T(n), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown %s %q, expected one of: %s", what, s, enumNames(names))
+}
+
+// enumNames lists the names of an enum in numeric order.
+func enumNames[T ~int64](names map[T]string) string {
+	var min, max T
+	first := true
+	for value := range names {
+		if first || value < min {
+			min = value
+		}
+		if first || value > max {
+			max = value
+		}
+		first = false
+	}
+	var list []string
+	for value := min; value <= max; value++ {
+		if name, ok := names[value]; ok {
+			list = append(list, name)
+		}
+	}
+	return strings.Join(list, ",")
+}
+
+// unmarshalEnumJSON accepts a JSON string holding a name or number, or a plain JSON number,
+// including numbers of values without a name.
+func unmarshalEnumJSON[T ~int64](names map[T]string, data []byte, what string) (T, error) {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return 0, fmt.Errorf("invalid %s %s", what, data)
+		}
+		s = strconv.FormatInt(n, 10)
+	}
+	return unmarshalEnum(func(s string) (T, error) { return parseEnum(names, s, what) }, s)
+}
+
+// OperationMode is the AC mode of a device
+type OperationMode int64
+
+const (
+	ModeAuto OperationMode = 0
+	ModeDry  OperationMode = 1
+	ModeCool OperationMode = 2
+	ModeHeat OperationMode = 3
+	ModeFan  OperationMode = 4
+)
+
+var operationModeNames = map[OperationMode]string{
+	ModeAuto: "auto",
+	ModeDry:  "dry",
+	ModeCool: "cool",
+	ModeHeat: "heat",
+	ModeFan:  "fan",
+}
+
+// ParseOperationMode parses an AC mode: auto,dry,cool,heat,fan
+func ParseOperationMode(s string) (OperationMode, error) {
+	return parseEnum(operationModeNames, s, "mode")
+}
+
+// String returns the name of the mode, or OperationMode(number) if it has no name
+func (m OperationMode) String() string {
+	return enumString(operationModeNames, m, "OperationMode")
+}
+
+// MarshalText returns the name of the mode, or its number if it has no name
+func (m OperationMode) MarshalText() ([]byte, error) {
+	return []byte(enumText(operationModeNames, m)), nil
+}
+
+// UnmarshalText accepts the name or number of a mode
+func (m *OperationMode) UnmarshalText(text []byte) (err error) {
+	*m, err = unmarshalEnum(ParseOperationMode, string(text))
+	return err
+}
+
+// MarshalJSON marshals the mode to its name, or to a JSON number if it has no name
+func (m OperationMode) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(operationModeNames, m)
+}
+
+// UnmarshalJSON accepts the name or number of a mode, as a JSON string or number
+func (m *OperationMode) UnmarshalJSON(data []byte) (err error) {
+	*m, err = unmarshalEnumJSON(operationModeNames, data, "mode")
+	return err
+}
+
+// EcoMode is the eco mode of a device
+type EcoMode int64
+
+const (
+	EcoModeAuto     EcoMode = 0
+	EcoModePowerful EcoMode = 1
+	EcoModeQuiet    EcoMode = 2
+)
+
+var ecoModeNames = map[EcoMode]string{
+	EcoModeAuto:     "auto",
+	EcoModePowerful: "powerful",
+	EcoModeQuiet:    "quiet",
+}
+
+// ParseEcoMode parses an eco mode: auto,powerful,quiet
+func ParseEcoMode(s string) (EcoMode, error) {
+	return parseEnum(ecoModeNames, s, "eco mode")
+}
+
+// String returns the name of the eco mode, or EcoMode(number) if it has no name
+func (m EcoMode) String() string {
+	return enumString(ecoModeNames, m, "EcoMode")
+}
+
+// MarshalText returns the name of the eco mode, or its number if it has no name
+func (m EcoMode) MarshalText() ([]byte, error) {
+	return []byte(enumText(ecoModeNames, m)), nil
+}
+
+// UnmarshalText accepts the name or number of a eco mode
+func (m *EcoMode) UnmarshalText(text []byte) (err error) {
+	*m, err = unmarshalEnum(ParseEcoMode, string(text))
+	return err
+}
+
+// MarshalJSON marshals the eco mode to its name, or to a JSON number if it has no name
+func (m EcoMode) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(ecoModeNames, m)
+}
+
+// UnmarshalJSON accepts the name or number of a eco mode, as a JSON string or number
+func (m *EcoMode) UnmarshalJSON(data []byte) (err error) {
+	*m, err = unmarshalEnumJSON(ecoModeNames, data, "eco mode")
+	return err
+}
+
+// FanSpeed is the fan speed of a device, auto or 1 (low) to 5 (high)
+type FanSpeed int64
+
+const (
+	FanSpeedAuto    FanSpeed = 0
+	FanSpeedLow     FanSpeed = 1
+	FanSpeedLowMid  FanSpeed = 2
+	FanSpeedMid     FanSpeed = 3
+	FanSpeedHighMid FanSpeed = 4
+	FanSpeedHigh    FanSpeed = 5
+)
+
+var fanSpeedNames = map[FanSpeed]string{
+	FanSpeedAuto:    "auto",
+	FanSpeedLow:     "1",
+	FanSpeedLowMid:  "2",
+	FanSpeedMid:     "3",
+	FanSpeedHighMid: "4",
+	FanSpeedHigh:    "5",
+}
+
+// ParseFanSpeed parses a fan speed: auto,1,2,3,4,5
+func ParseFanSpeed(s string) (FanSpeed, error) {
+	return parseEnum(fanSpeedNames, s, "fan speed")
+}
+
+// String returns the name of the fan speed, or FanSpeed(number) if it has no name
+func (s FanSpeed) String() string {
+	return enumString(fanSpeedNames, s, "FanSpeed")
+}
+
+// MarshalText returns the name of the fan speed, or its number if it has no name
+func (s FanSpeed) MarshalText() ([]byte, error) {
+	return []byte(enumText(fanSpeedNames, s)), nil
+}
+
+// UnmarshalText accepts the name or number of a fan speed
+func (s *FanSpeed) UnmarshalText(text []byte) (err error) {
+	*s, err = unmarshalEnum(ParseFanSpeed, string(text))
+	return err
+}
+
+// MarshalJSON marshals the fan speed to its name, or to a JSON number if it has no name
+func (s FanSpeed) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(fanSpeedNames, s)
+}
+
+// UnmarshalJSON accepts the name or number of a fan speed, as a JSON string or number
+func (s *FanSpeed) UnmarshalJSON(data []byte) (err error) {
+	*s, err = unmarshalEnumJSON(fanSpeedNames, data, "fan speed")
+	return err
+}
+
+// PowerState defines if the AC is on or off
+type PowerState int64
+
+const (
+	PowerOff PowerState = 0
+	PowerOn  PowerState = 1
+)
+
+var powerStateNames = map[PowerState]string{
+	PowerOff: "off",
+	PowerOn:  "on",
+}
+
+// ParsePowerState parses a power state: off,on
+func ParsePowerState(s string) (PowerState, error) {
+	return parseEnum(powerStateNames, s, "power state")
+}
+
+// String returns the name of the power state, or PowerState(number) if it has no name
+func (p PowerState) String() string {
+	return enumString(powerStateNames, p, "PowerState")
+}
+
+// MarshalText returns the name of the power state, or its number if it has no name
+func (p PowerState) MarshalText() ([]byte, error) {
+	return []byte(enumText(powerStateNames, p)), nil
+}
+
+// UnmarshalText accepts the name or number of a power state
+func (p *PowerState) UnmarshalText(text []byte) (err error) {
+	*p, err = unmarshalEnum(ParsePowerState, string(text))
+	return err
+}
+
+// MarshalJSON marshals the power state to its name, or to a JSON number if it has no name
+func (p PowerState) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(powerStateNames, p)
+}
+
+// UnmarshalJSON accepts the name or number of a power state, as a JSON string or number
+func (p *PowerState) UnmarshalJSON(data []byte) (err error) {
+	*p, err = unmarshalEnumJSON(powerStateNames, data, "power state")
+	return err
+}
+
+// HistoryPeriod is the time interval to fetch history data for
+type HistoryPeriod int64
+
+const (
+	HistoryDay   HistoryPeriod = 0
+	HistoryWeek  HistoryPeriod = 1
+	HistoryMonth HistoryPeriod = 2
+	HistoryYear  HistoryPeriod = 3
+)
+
+var historyPeriodNames = map[HistoryPeriod]string{
+	HistoryDay:   "day",
+	HistoryWeek:  "week",
+	HistoryMonth: "month",
+	HistoryYear:  "year",
+}
+
+// ParseHistoryPeriod parses a history period: day,week,month,year
+func ParseHistoryPeriod(s string) (HistoryPeriod, error) {
+	return parseEnum(historyPeriodNames, s, "history period")
+}
+
+// String returns the name of the period, or HistoryPeriod(number) if it has no name
+func (p HistoryPeriod) String() string {
+	return enumString(historyPeriodNames, p, "HistoryPeriod")
+}
+
+// MarshalText returns the name of the period, or its number if it has no name
+func (p HistoryPeriod) MarshalText() ([]byte, error) {
+	return []byte(enumText(historyPeriodNames, p)), nil
+}
+
+// UnmarshalText accepts the name or number of a period
+func (p *HistoryPeriod) UnmarshalText(text []byte) (err error) {
+	*p, err = unmarshalEnum(ParseHistoryPeriod, string(text))
+	return err
+}
+
+// MarshalJSON marshals the period to its name, or to a JSON number if it has no name
+func (p HistoryPeriod) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(historyPeriodNames, p)
+}
+
+// UnmarshalJSON accepts the name or number of a period, as a JSON string or number
+func (p *HistoryPeriod) UnmarshalJSON(data []byte) (err error) {
+	*p, err = unmarshalEnumJSON(historyPeriodNames, data, "history period")
+	return err
+}
+
+// Mode returns the AC mode
+func (p DeviceParameters) Mode() OperationMode {
+	return OperationMode(p.OperationMode)
+}
+
+// Power returns whether the AC is on or off
+func (p DeviceParameters) Power() PowerState {
+	return PowerState(p.Operate)
+}
+
+// Fan returns the fan speed
+func (p DeviceParameters) Fan() FanSpeed {
+	return FanSpeed(p.FanSpeed)
+}
+
+// Eco returns the eco mode
+func (p DeviceParameters) Eco() EcoMode {
+	return EcoMode(p.EcoMode)
+}
+
+// NanoeMode returns the nanoe mode
+func (p DeviceParameters) NanoeMode() NanoeMode {
+	return NanoeMode(p.Nanoe)
+}
+
+// EcoNaviState returns the ecoNavi state
+func (p DeviceParameters) EcoNaviState() FeatureState {
+	return FeatureState(p.EcoNavi)
+}
+
+// IAutoState returns the iAuto-X state
+func (p DeviceParameters) IAutoState() FeatureState {
+	return FeatureState(p.Iauto)
+}
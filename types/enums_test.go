@@ -0,0 +1,83 @@
+package types_test
+
+import (
+	"encoding/json"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseOperationMode(t *testing.T) {
+	mode, err := types.ParseOperationMode("heat")
+	assert.NoError(t, err)
+	assert.Equal(t, types.ModeHeat, mode)
+
+	_, err = types.ParseOperationMode("hat")
+	assert.ErrorContains(t, err, "auto,dry,cool,heat,fan")
+
+	_, err = types.ParseOperationMode("7")
+	assert.Error(t, err)
+}
+
+func TestEnums_String(t *testing.T) {
+	assert.Equal(t, "cool", types.ModeCool.String())
+	assert.Equal(t, "powerful", types.EcoModePowerful.String())
+	assert.Equal(t, "auto", types.FanSpeedAuto.String())
+	assert.Equal(t, "4", types.FanSpeedHighMid.String())
+	assert.Equal(t, "on", types.PowerOn.String())
+	assert.Equal(t, "month", types.HistoryMonth.String())
+	assert.Equal(t, "OperationMode(9)", types.OperationMode(9).String())
+}
+
+func TestEnums_JSON(t *testing.T) {
+	type state struct {
+		Power types.PowerState    `json:"power"`
+		Mode  types.OperationMode `json:"mode"`
+		Fan   types.FanSpeed      `json:"fan"`
+		Eco   types.EcoMode       `json:"eco"`
+	}
+
+	data, err := json.Marshal(state{types.PowerOn, types.ModeDry, types.FanSpeedHigh, types.EcoModeQuiet})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"power":"on","mode":"dry","fan":"5","eco":"quiet"}`, string(data))
+
+	decoded := state{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"power":1,"mode":"heat","fan":"auto","eco":1}`), &decoded))
+	assert.Equal(t, state{types.PowerOn, types.ModeHeat, types.FanSpeedAuto, types.EcoModePowerful}, decoded)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"mode":"hat"}`), &decoded))
+}
+
+func TestEnums_Text(t *testing.T) {
+	var period types.HistoryPeriod
+	assert.NoError(t, period.UnmarshalText([]byte("week")))
+	assert.Equal(t, types.HistoryWeek, period)
+
+	text, err := types.VerticalSwingDownMid.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, "down-mid", string(text))
+}
+
+func TestEnums_UnknownValues(t *testing.T) {
+	snapshot := types.Snapshot{VerticalSwing: 7, FanSpeed: types.FanSpeedAuto, Nanoe: 9}
+
+	data, err := json.Marshal(snapshot)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"airSwingUD":7`)
+	assert.Contains(t, string(data), `"nanoe":9`)
+
+	decoded := types.Snapshot{}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, snapshot, decoded)
+
+	text, err := types.HorizontalSwing(3).MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, "3", string(text))
+	var swing types.HorizontalSwing
+	assert.NoError(t, swing.UnmarshalText(text))
+	assert.Equal(t, types.HorizontalSwing(3), swing)
+
+	// user input is still limited to the known values
+	_, err = types.ParseHorizontalSwing("3")
+	assert.Error(t, err)
+}
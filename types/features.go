@@ -0,0 +1,99 @@
+package types
+
+// NanoeMode is the state of the nanoe air purifier
+type NanoeMode int64
+
+const (
+	NanoeUnavailable NanoeMode = 0
+	NanoeOff         NanoeMode = 1
+	NanoeOn          NanoeMode = 2
+	NanoeModeG       NanoeMode = 3
+	NanoeAll         NanoeMode = 4
+)
+
+var nanoeModeNames = map[NanoeMode]string{
+	NanoeUnavailable: "unavailable",
+	NanoeOff:         "off",
+	NanoeOn:          "on",
+	NanoeModeG:       "mode-g",
+	NanoeAll:         "all",
+}
+
+// ParseNanoeMode parses a nanoe mode: unavailable,off,on,mode-g,all
+func ParseNanoeMode(s string) (NanoeMode, error) {
+	return parseEnum(nanoeModeNames, s, "nanoe mode")
+}
+
+// String returns the name of the nanoe mode, or NanoeMode(number) if it has no name
+func (m NanoeMode) String() string {
+	return enumString(nanoeModeNames, m, "NanoeMode")
+}
+
+// MarshalText returns the name of the nanoe mode, or its number if it has no name
+func (m NanoeMode) MarshalText() ([]byte, error) {
+	return []byte(enumText(nanoeModeNames, m)), nil
+}
+
+// UnmarshalText accepts the name or number of a nanoe mode
+func (m *NanoeMode) UnmarshalText(text []byte) (err error) {
+	*m, err = unmarshalEnum(ParseNanoeMode, string(text))
+	return err
+}
+
+// MarshalJSON marshals the nanoe mode to its name, or to a JSON number if it has no name
+func (m NanoeMode) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(nanoeModeNames, m)
+}
+
+// UnmarshalJSON accepts the name or number of a nanoe mode, as a JSON string or number
+func (m *NanoeMode) UnmarshalJSON(data []byte) (err error) {
+	*m, err = unmarshalEnumJSON(nanoeModeNames, data, "nanoe mode")
+	return err
+}
+
+// FeatureState is the state of an on/off feature such as ecoNavi and iAuto-X
+type FeatureState int64
+
+const (
+	FeatureUnavailable FeatureState = 0
+	FeatureOff         FeatureState = 1
+	FeatureOn          FeatureState = 2
+)
+
+var featureStateNames = map[FeatureState]string{
+	FeatureUnavailable: "unavailable",
+	FeatureOff:         "off",
+	FeatureOn:          "on",
+}
+
+// ParseFeatureState parses a feature state: unavailable,off,on
+func ParseFeatureState(s string) (FeatureState, error) {
+	return parseEnum(featureStateNames, s, "feature state")
+}
+
+// String returns the name of the state, or FeatureState(number) if it has no name
+func (f FeatureState) String() string {
+	return enumString(featureStateNames, f, "FeatureState")
+}
+
+// MarshalText returns the name of the state, or its number if it has no name
+func (f FeatureState) MarshalText() ([]byte, error) {
+	return []byte(enumText(featureStateNames, f)), nil
+}
+
+// UnmarshalText accepts the name or number of a state
+func (f *FeatureState) UnmarshalText(text []byte) (err error) {
+	*f, err = unmarshalEnum(ParseFeatureState, string(text))
+	return err
+}
+
+// MarshalJSON marshals the state to its name, or to a JSON number if it has no name
+func (f FeatureState) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(featureStateNames, f)
+}
+
+// UnmarshalJSON accepts the name or number of a state, as a JSON string or number
+func (f *FeatureState) UnmarshalJSON(data []byte) (err error) {
+	*f, err = unmarshalEnumJSON(featureStateNames, data, "feature state")
+	return err
+}
@@ -0,0 +1,41 @@
+package types
+
+import "time"
+
+// Snapshot is the controllable state of a device, as saved by Snapshot and replayed by Restore
+// Features the device does not have are stored as unavailable and not restored, horizontal
+// swing is nil for devices without it
+type Snapshot struct {
+	DeviceGUID      string           `json:"deviceGuid"`
+	Time            time.Time        `json:"time"`
+	Power           PowerState       `json:"operate"`
+	Mode            OperationMode    `json:"operationMode"`
+	Temperature     float64          `json:"temperatureSet"`
+	FanSpeed        FanSpeed         `json:"fanSpeed"`
+	VerticalSwing   VerticalSwing    `json:"airSwingUD"`
+	HorizontalSwing *HorizontalSwing `json:"airSwingLR,omitempty"`
+	EcoMode         EcoMode          `json:"ecoMode"`
+	Nanoe           NanoeMode        `json:"nanoe"`
+	EcoNavi         FeatureState     `json:"ecoNavi"`
+	IAuto           FeatureState     `json:"iAuto"`
+}
+
+// NewSnapshot captures the controllable state of a device status
+func NewSnapshot(device Device) Snapshot {
+	p := device.Parameters
+	horizontal := p.HorizontalSwing()
+	return Snapshot{
+		DeviceGUID:      device.DeviceGUID,
+		Time:            time.Now().UTC().Truncate(time.Second),
+		Power:           p.Power(),
+		Mode:            p.Mode(),
+		Temperature:     p.TemperatureSet,
+		FanSpeed:        p.Fan(),
+		VerticalSwing:   p.VerticalSwing(),
+		HorizontalSwing: &horizontal,
+		EcoMode:         p.Eco(),
+		Nanoe:           p.NanoeMode(),
+		EcoNavi:         p.EcoNaviState(),
+		IAuto:           p.IAutoState(),
+	}
+}
@@ -0,0 +1,54 @@
+package types
+
+// DeviceSummary is an overview of a device and its current state
+// Temperatures the unit does not measure are nil
+type DeviceSummary struct {
+	GUID               string        `json:"guid"`
+	Name               string        `json:"name"`
+	Group              string        `json:"group"`
+	Model              string        `json:"model"`
+	Type               string        `json:"type"`
+	Online             bool          `json:"online"`
+	Power              PowerState    `json:"power"`
+	Mode               OperationMode `json:"mode"`
+	TemperatureSet     float64       `json:"temperatureSet"`
+	InsideTemperature  *float64      `json:"insideTemperature"`
+	OutsideTemperature *float64      `json:"outsideTemperature"`
+}
+
+// Summaries returns a summary of every device in the groups, in the order of the groups
+func (g Groups) Summaries() []DeviceSummary {
+	summaries := []DeviceSummary{}
+	for _, group := range g.Groups {
+		for _, device := range group.Devices {
+			summaries = append(summaries, device.Summary(group.GroupName))
+		}
+	}
+	return summaries
+}
+
+// Summary returns an overview of the device, which belongs to the named group
+func (d Device) Summary(group string) DeviceSummary {
+	p := d.Parameters
+	return DeviceSummary{
+		GUID:               d.DeviceGUID,
+		Name:               d.DeviceName,
+		Group:              group,
+		Model:              d.DeviceModuleNumber,
+		Type:               d.DeviceType,
+		Online:             p.Reachable(),
+		Power:              p.Power(),
+		Mode:               p.Mode(),
+		TemperatureSet:     p.TemperatureSet,
+		InsideTemperature:  Measurement(p.InsideTemperature, TemperatureUnavailable),
+		OutsideTemperature: Measurement(p.OutsideTemperature, TemperatureUnavailable),
+	}
+}
+
+// Measurement returns nil if the value is the one reported when there is no measurement
+func Measurement(value float64, unavailable float64) *float64 {
+	if value == unavailable {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,187 @@
+package types
+
+// VerticalSwing is the vertical position of the louvres (airSwingUD)
+type VerticalSwing int64
+
+const (
+	VerticalSwingAuto    VerticalSwing = -1
+	VerticalSwingUp      VerticalSwing = 0
+	VerticalSwingDown    VerticalSwing = 1
+	VerticalSwingMid     VerticalSwing = 2
+	VerticalSwingUpMid   VerticalSwing = 3
+	VerticalSwingDownMid VerticalSwing = 4
+)
+
+var verticalSwingNames = map[VerticalSwing]string{
+	VerticalSwingAuto:    "auto",
+	VerticalSwingUp:      "up",
+	VerticalSwingUpMid:   "up-mid",
+	VerticalSwingMid:     "mid",
+	VerticalSwingDownMid: "down-mid",
+	VerticalSwingDown:    "down",
+}
+
+// ParseVerticalSwing parses a vertical swing position: auto,up,up-mid,mid,down-mid,down
+func ParseVerticalSwing(s string) (VerticalSwing, error) {
+	return parseEnum(verticalSwingNames, s, "vertical swing")
+}
+
+// String returns the name of the position, or VerticalSwing(number) if it has no name
+func (v VerticalSwing) String() string {
+	return enumString(verticalSwingNames, v, "VerticalSwing")
+}
+
+// MarshalText returns the name of the position, or its number if it has no name
+func (v VerticalSwing) MarshalText() ([]byte, error) {
+	return []byte(enumText(verticalSwingNames, v)), nil
+}
+
+// UnmarshalText accepts the name or number of a position
+func (v *VerticalSwing) UnmarshalText(text []byte) (err error) {
+	*v, err = unmarshalEnum(ParseVerticalSwing, string(text))
+	return err
+}
+
+// MarshalJSON marshals the position to its name, or to a JSON number if it has no name
+func (v VerticalSwing) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(verticalSwingNames, v)
+}
+
+// UnmarshalJSON accepts the name or number of a position, as a JSON string or number
+func (v *VerticalSwing) UnmarshalJSON(data []byte) (err error) {
+	*v, err = unmarshalEnumJSON(verticalSwingNames, data, "vertical swing")
+	return err
+}
+
+// HorizontalSwing is the horizontal position of the louvres (airSwingLR)
+type HorizontalSwing int64
+
+const (
+	HorizontalSwingAuto     HorizontalSwing = -1
+	HorizontalSwingRight    HorizontalSwing = 0
+	HorizontalSwingLeft     HorizontalSwing = 1
+	HorizontalSwingMid      HorizontalSwing = 2
+	HorizontalSwingRightMid HorizontalSwing = 4
+	HorizontalSwingLeftMid  HorizontalSwing = 5
+)
+
+var horizontalSwingNames = map[HorizontalSwing]string{
+	HorizontalSwingAuto:     "auto",
+	HorizontalSwingLeft:     "left",
+	HorizontalSwingLeftMid:  "left-mid",
+	HorizontalSwingMid:      "mid",
+	HorizontalSwingRightMid: "right-mid",
+	HorizontalSwingRight:    "right",
+}
+
+// ParseHorizontalSwing parses a horizontal swing position: auto,left,left-mid,mid,right-mid,right
+func ParseHorizontalSwing(s string) (HorizontalSwing, error) {
+	return parseEnum(horizontalSwingNames, s, "horizontal swing")
+}
+
+// String returns the name of the position, or HorizontalSwing(number) if it has no name
+func (h HorizontalSwing) String() string {
+	return enumString(horizontalSwingNames, h, "HorizontalSwing")
+}
+
+// MarshalText returns the name of the position, or its number if it has no name
+func (h HorizontalSwing) MarshalText() ([]byte, error) {
+	return []byte(enumText(horizontalSwingNames, h)), nil
+}
+
+// UnmarshalText accepts the name or number of a position
+func (h *HorizontalSwing) UnmarshalText(text []byte) (err error) {
+	*h, err = unmarshalEnum(ParseHorizontalSwing, string(text))
+	return err
+}
+
+// MarshalJSON marshals the position to its name, or to a JSON number if it has no name
+func (h HorizontalSwing) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(horizontalSwingNames, h)
+}
+
+// UnmarshalJSON accepts the name or number of a position, as a JSON string or number
+func (h *HorizontalSwing) UnmarshalJSON(data []byte) (err error) {
+	*h, err = unmarshalEnumJSON(horizontalSwingNames, data, "horizontal swing")
+	return err
+}
+
+// AutoSwingMode defines which louvres swing automatically (fanAutoMode)
+type AutoSwingMode int64
+
+const (
+	AutoSwingBoth       AutoSwingMode = 0
+	AutoSwingOff        AutoSwingMode = 1
+	AutoSwingVertical   AutoSwingMode = 2
+	AutoSwingHorizontal AutoSwingMode = 3
+)
+
+var autoSwingModeNames = map[AutoSwingMode]string{
+	AutoSwingBoth:       "both",
+	AutoSwingOff:        "off",
+	AutoSwingVertical:   "vertical",
+	AutoSwingHorizontal: "horizontal",
+}
+
+// ParseAutoSwingMode parses an auto swing mode: both,off,vertical,horizontal
+func ParseAutoSwingMode(s string) (AutoSwingMode, error) {
+	return parseEnum(autoSwingModeNames, s, "auto swing mode")
+}
+
+// String returns the name of the auto swing mode, or AutoSwingMode(number) if it has no name
+func (m AutoSwingMode) String() string {
+	return enumString(autoSwingModeNames, m, "AutoSwingMode")
+}
+
+// MarshalText returns the name of the auto swing mode, or its number if it has no name
+func (m AutoSwingMode) MarshalText() ([]byte, error) {
+	return []byte(enumText(autoSwingModeNames, m)), nil
+}
+
+// UnmarshalText accepts the name or number of a auto swing mode
+func (m *AutoSwingMode) UnmarshalText(text []byte) (err error) {
+	*m, err = unmarshalEnum(ParseAutoSwingMode, string(text))
+	return err
+}
+
+// MarshalJSON marshals the auto swing mode to its name, or to a JSON number if it has no name
+func (m AutoSwingMode) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(autoSwingModeNames, m)
+}
+
+// UnmarshalJSON accepts the name or number of a auto swing mode, as a JSON string or number
+func (m *AutoSwingMode) UnmarshalJSON(data []byte) (err error) {
+	*m, err = unmarshalEnumJSON(autoSwingModeNames, data, "auto swing mode")
+	return err
+}
+
+// NewAutoSwingMode returns the auto swing mode matching the given louvre positions.
+func NewAutoSwingMode(vertical VerticalSwing, horizontal HorizontalSwing) AutoSwingMode {
+	switch {
+	case vertical == VerticalSwingAuto && horizontal == HorizontalSwingAuto:
+		return AutoSwingBoth
+	case vertical == VerticalSwingAuto:
+		return AutoSwingVertical
+	case horizontal == HorizontalSwingAuto:
+		return AutoSwingHorizontal
+	}
+	return AutoSwingOff
+}
+
+// VerticalSwing returns the vertical louvre position, taking auto swing into account.
+func (p DeviceParameters) VerticalSwing() VerticalSwing {
+	mode := AutoSwingMode(p.FanAutoMode)
+	if mode == AutoSwingBoth || mode == AutoSwingVertical {
+		return VerticalSwingAuto
+	}
+	return VerticalSwing(p.AirSwingUD)
+}
+
+// HorizontalSwing returns the horizontal louvre position, taking auto swing into account.
+func (p DeviceParameters) HorizontalSwing() HorizontalSwing {
+	mode := AutoSwingMode(p.FanAutoMode)
+	if mode == AutoSwingBoth || mode == AutoSwingHorizontal {
+		return HorizontalSwingAuto
+	}
+	return HorizontalSwing(p.AirSwingLR)
+}
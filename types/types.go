@@ -1,66 +1,6 @@
 package types
 
-// HistoryDataMode maps out the time intervals to fetch history data
-var HistoryDataMode = map[string]int64{
-	"day":   0,
-	"week":  1,
-	"month": 2,
-	"year":  3,
-}
-
-// Modes define the different AC modes the device can be in
-var Modes = map[string]int64{
-	"auto": 0,
-	"dry":  1,
-	"cool": 2,
-	"heat": 3,
-	"fan":  4,
-}
-
-// ModesReverse define the different AC modes the device can be in
-var ModesReverse = map[int64]string{
-	0: "auto",
-	1: "dry",
-	2: "cool",
-	3: "heat",
-	4: "fan",
-}
-
-var EcoMode = map[string]int64{
-	"auto":     0,
-	"powerful": 1,
-	"quiet":    2,
-}
-
-var EcoModeReverse = map[int64]string{
-	0: "auto",
-	1: "powerful",
-	2: "quiet",
-}
-
-var FanSpeed = map[string]int64{
-	"auto": 0,
-	"1":    1,
-	"2":    2,
-	"3":    3,
-	"4":    4,
-	"5":    5,
-}
-
-var FanSpeedReverse = map[int64]string{
-	0: "auto",
-	1: "1",
-	2: "2",
-	3: "3",
-	4: "4",
-	5: "5",
-}
-
-// Operate defines if the AC is on or off
-var Operate = map[int64]string{
-	0: "off",
-	1: "on",
-}
+import "encoding/json"
 
 // Session is a login session structure
 type Session struct {
@@ -153,42 +93,66 @@ type DeviceParameters struct {
 	UpdateTime              int64   `json:"updateTime"`
 }
 
+// UnmarshalJSON decodes the parameters, online is assumed if the API leaves it out
+func (p *DeviceParameters) UnmarshalJSON(data []byte) error {
+	type parameters DeviceParameters
+	decoded := parameters{Online: true}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*p = DeviceParameters(decoded)
+	return nil
+}
+
+// Reachable reports whether the cloud is in contact with the unit
+func (p DeviceParameters) Reachable() bool {
+	return p.Online && p.DevRacCommunicateStatus == 0
+}
+
 // Device is Panasonic device
+// Capability flags are reported either as booleans or as 0/1, see Flag
 type Device struct {
-	AirSwingLR         bool             `json:"airSwingLR"`
-	AutoMode           bool             `json:"autoMode"`
+	AirSwingLR         Flag             `json:"airSwingLR"`
+	AutoMode           Flag             `json:"autoMode"`
 	AutoTempMax        int64            `json:"autoTempMax"`
 	AutoTempMin        int64            `json:"autoTempMin"`
-	CoolMode           bool             `json:"coolMode"`
-	CoolTempMax        int64            `json:"coolTeampMax"`
+	CoolMode           Flag             `json:"coolMode"`
+	CoolTempMax        int64            `json:"coolTempMax"`
 	CoolTempMin        int64            `json:"coolTempMin"`
 	DeviceGUID         string           `json:"deviceGuid"`
 	DeviceHashGUID     string           `json:"deviceHashGuid"`
 	DeviceModuleNumber string           `json:"deviceModuleNumber"`
 	DeviceName         string           `json:"deviceName"`
 	DeviceType         string           `json:"deviceType"`
-	DryMode            bool             `json:"dryMode"`
+	DryMode            Flag             `json:"dryMode"`
 	DryTempMax         int64            `json:"dryTempMax"`
 	DryTempMin         int64            `json:"dryTempMin"`
 	EcoFunction        int64            `json:"ecoFunction"`
-	EcoNavi            bool             `json:"ecoNavi"`
+	EcoNavi            Flag             `json:"ecoNavi"`
 	FanDirectionMode   int64            `json:"fanDirectionMode"`
-	FanMode            bool             `json:"fanMode"`
+	FanMode            Flag             `json:"fanMode"`
 	FanSpeedMode       int64            `json:"fanSpeedMode"`
-	HeatMode           bool             `json:"heatMode"`
+	HeatMode           Flag             `json:"heatMode"`
 	HeatTempMax        int64            `json:"heatTempMax"`
-	HeatTempMin        int64            `json:"heatTeampMin"`
-	IautoX             bool             `json:"iAutoX"`
-	ModeAvlAutoMode    bool             `json:"modeAvlList.autoMode"`
-	ModeAvlFanMode     bool             `json:"modeAvlList.fanMode"`
-	Nanoe              bool             `json:"nanoe"`
-	QuietMode          bool             `json:"quietMode"`
+	HeatTempMin        int64            `json:"heatTempMin"`
+	IautoX             Flag             `json:"iAutoX"`
+	ModeAvailability   ModeAvailability `json:"modeAvlList"`
+	Nanoe              Flag             `json:"nanoe"`
+	Permission         int64            `json:"permission"`
+	PowerfulMode       Flag             `json:"powerfulMode"`
+	QuietMode          Flag             `json:"quietMode"`
 	SummerHouse        int64            `json:"summerHouse"`
 	TemperatureUnit    int64            `json:"temperatureUnit"`
 	TimeStamp          int64            `json:"timestamp"`
 	Parameters         DeviceParameters `json:"parameters"`
 }
 
+// ModeAvailability is the nested modeAvlList object of a device
+type ModeAvailability struct {
+	AutoMode Flag `json:"autoMode"`
+	FanMode  Flag `json:"fanMode"`
+}
+
 // History is a list of HistoryEntry points with measurements
 type History struct {
 	EnergyConsumption  float64        `json:"energyConsumption"`
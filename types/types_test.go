@@ -0,0 +1,116 @@
+package types_test
+
+import (
+	"encoding/json"
+	"github.com/jesper-nord/go-pcc/types"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func loadGroupsFixture(t *testing.T) types.Groups {
+	data, err := os.ReadFile("testdata/groups.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := types.Groups{}
+	if err := json.Unmarshal(data, &groups); err != nil {
+		t.Fatal(err)
+	}
+	return groups
+}
+
+func TestDevice_UnmarshalsAllCapabilities(t *testing.T) {
+	device := loadGroupsFixture(t).Groups[0].Devices[0]
+
+	assert.Equal(t, "CZ-CAPWFC1+B8B7F1B3E326", device.DeviceGUID)
+	assert.Equal(t, "Alaior-home", device.DeviceName)
+	assert.Equal(t, "S-125PU2E5B", device.DeviceModuleNumber)
+	assert.Equal(t, "4", device.DeviceType)
+	assert.Equal(t, int64(3), device.Permission)
+
+	assert.Equal(t, types.ModeAvailability{AutoMode: true, FanMode: true}, device.ModeAvailability)
+
+	assert.Equal(t, types.Capabilities{
+		Auto:             types.ModeCapability{Available: true, Temperature: types.TemperatureRange{Min: 17, Max: 27}},
+		Dry:              types.ModeCapability{Available: true, Temperature: types.TemperatureRange{Min: 18, Max: 30}},
+		Cool:             types.ModeCapability{Available: true, Temperature: types.TemperatureRange{Min: 18, Max: 30}},
+		Heat:             types.ModeCapability{Available: true, Temperature: types.TemperatureRange{Min: 16, Max: 30}},
+		Fan:              types.ModeCapability{Available: true},
+		FanSpeeds:        5,
+		FanDirections:    5,
+		QuietMode:        true,
+		PowerfulMode:     true,
+		Nanoe:            true,
+		EcoNavi:          false,
+		IAutoX:           false,
+		AirSwingLR:       true,
+		ModeAvailability: types.ModeAvailability{AutoMode: true, FanMode: true},
+	}, device.Capabilities())
+}
+
+func TestDevice_CapabilitiesFromModeAvailability(t *testing.T) {
+	// fanMode and autoMode are false at the top level, only modeAvlList enables them
+	device := types.Device{ModeAvailability: types.ModeAvailability{FanMode: true}}
+	assert.True(t, device.Capabilities().Fan.Available)
+	assert.False(t, device.Capabilities().Auto.Available)
+
+	device = types.Device{ModeAvailability: types.ModeAvailability{AutoMode: true}}
+	assert.True(t, device.Capabilities().Auto.Available)
+	assert.False(t, device.Capabilities().Fan.Available)
+
+	device = types.Device{FanMode: true}
+	assert.True(t, device.Capabilities().Fan.Available)
+}
+
+func TestFlag_AcceptsBooleansAndIntegers(t *testing.T) {
+	payload := `{"autoMode":1,"heatMode":true,"coolMode":0,"dryMode":false,"fanMode":null,"nanoe":"1","modeAvlList":{"autoMode":true,"fanMode":0}}`
+
+	device := types.Device{}
+	err := json.Unmarshal([]byte(payload), &device)
+
+	assert.NoError(t, err)
+	assert.True(t, bool(device.AutoMode))
+	assert.True(t, bool(device.HeatMode))
+	assert.False(t, bool(device.CoolMode))
+	assert.False(t, bool(device.DryMode))
+	assert.False(t, bool(device.FanMode))
+	assert.True(t, bool(device.Nanoe))
+	assert.True(t, bool(device.ModeAvailability.AutoMode))
+	assert.False(t, bool(device.ModeAvailability.FanMode))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"autoMode":"yes"}`), &device))
+}
+
+func TestTemperatureRange(t *testing.T) {
+	heat := types.TemperatureRange{Min: 16, Max: 30}
+
+	assert.True(t, heat.Contains(16))
+	assert.True(t, heat.Contains(30))
+	assert.False(t, heat.Contains(30.5))
+	assert.True(t, types.TemperatureRange{}.Contains(99))
+	assert.True(t, types.ValidTemperatureStep(21.5))
+	assert.False(t, types.ValidTemperatureStep(21.25))
+}
+
+func TestGroups_Summaries(t *testing.T) {
+	groups := loadGroupsFixture(t)
+	groups.Groups[0].Devices[0].Parameters.OutsideTemperature = types.TemperatureUnavailable
+
+	summaries := groups.Summaries()
+
+	assert.Len(t, summaries, 1)
+	summary := summaries[0]
+	assert.Equal(t, "CZ-CAPWFC1+B8B7F1B3E326", summary.GUID)
+	assert.Equal(t, "Alaior-home", summary.Name)
+	assert.Equal(t, "My House", summary.Group)
+	assert.Equal(t, "S-125PU2E5B", summary.Model)
+	assert.True(t, summary.Online)
+	assert.Equal(t, types.PowerOn, summary.Power)
+	assert.Equal(t, types.ModeAuto, summary.Mode)
+	assert.Equal(t, 19.5, summary.TemperatureSet)
+	assert.Nil(t, summary.OutsideTemperature)
+
+	groups.Groups[0].Devices[0].Parameters.Online = false
+	assert.False(t, groups.Summaries()[0].Online)
+}